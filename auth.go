@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/chris-langager/translationsdb/translations"
+)
+
+const sessionCookieName = "session"
+
+// paths anyone can reach without signing in
+var publicPaths = []string{"/login", "/register"}
+
+// Sessions are kept in memory, restarting the server signs everyone out
+type Sessions struct {
+	mu             sync.RWMutex
+	userIdsByToken map[string]string
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{
+		userIdsByToken: map[string]string{},
+	}
+}
+
+func (o *Sessions) Start(w http.ResponseWriter, userId string) error {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	o.mu.Lock()
+	o.userIdsByToken[token] = userId
+	o.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (o *Sessions) End(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err == nil {
+		o.mu.Lock()
+		delete(o.userIdsByToken, cookie.Value)
+		o.mu.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   sessionCookieName,
+		Path:   "/",
+		MaxAge: -1,
+	})
+}
+
+func (o *Sessions) UserId(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return "", false
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	userId, ok := o.userIdsByToken[cookie.Value]
+	return userId, ok
+}
+
+// authenticate puts the signed in user into the request context as the actor, from either
+// an "Authorization: Bearer <api token>" header or a session cookie
+func authenticate(eventStore translations.EventStore, sessions *Sessions, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			user, err := translations.AuthenticateApiToken(r.Context(), eventStore, token)
+			if err == translations.ErrorInvalidCredentials {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				panic(err)
+			}
+
+			next.ServeHTTP(w, r.WithContext(translations.WithActor(r.Context(), user.Id)))
+			return
+		}
+
+		if userId, ok := sessions.UserId(r); ok {
+			next.ServeHTTP(w, r.WithContext(translations.WithActor(r.Context(), userId)))
+			return
+		}
+
+		if translations.Contains(publicPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// htmx swaps the response into the page, so it has to be told to navigate instead
+		if r.Header.Get("HX-Request") != "" {
+			w.Header().Set("HX-Redirect", "/login")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chris-langager/translationsdb/translations"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	eventStore := translations.NewInMemoryEventStore()
+	write := func(events []translations.Event, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = eventStore.Write(ctx, nil, translations.AnyVersion, events...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(translations.RegisterUser(eventStore)(ctx, translations.RegisterUserInput{Username: "alice", Password: "secret123"}))
+	user, err := translations.Authenticate(ctx, eventStore, "alice", "secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := translations.Authenticate(ctx, eventStore, "alice", "wrong"); err != translations.ErrorInvalidCredentials {
+		t.Errorf("expected a wrong password to be rejected, got %v", err)
+	}
+
+	token, err := translations.NewApiToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(translations.IssueApiToken(eventStore)(ctx, translations.IssueApiTokenInput{UserId: user.Id, Name: "ci", Token: token}))
+
+	sessions := NewSessions()
+	handler := authenticate(eventStore, sessions, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(translations.GetActor(r.Context())))
+	}))
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// signing in starts a session that identifies the user until it's ended
+	w := httptest.NewRecorder()
+	err = sessions.Start(w, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := w.Result().Cookies()[0]
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+	if w := serve(r); w.Code != http.StatusOK || w.Body.String() != user.Id {
+		t.Errorf("expected the session's user as the actor, got %d %q", w.Code, w.Body.String())
+	}
+	sessions.End(httptest.NewRecorder(), r)
+	if w := serve(r); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Errorf("expected an ended session to be sent to /login, got %d", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("HX-Request", "true")
+	if w := serve(r); w.Code != http.StatusUnauthorized || w.Header().Get("HX-Redirect") != "/login" {
+		t.Errorf("expected htmx to be told to go to /login, got %d", w.Code)
+	}
+	for _, path := range publicPaths {
+		if w := serve(httptest.NewRequest("GET", path, nil)); w.Code != http.StatusOK || w.Body.String() != translations.GetActor(ctx) {
+			t.Errorf("expected %s to be reachable without an actor, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+
+	bearer := func(token string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+	if w := serve(bearer(token)); w.Code != http.StatusOK || w.Body.String() != user.Id {
+		t.Errorf("expected the token's user as the actor, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(bearer("tdb_wrong")); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected an unknown token to be unauthorized, got %d", w.Code)
+	}
+
+	users, err := translations.GetUsers(ctx, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := range users.UsersById[user.Id].ApiTokensById {
+		write(translations.RevokeApiToken(eventStore)(ctx, translations.RevokeApiTokenInput{UserId: user.Id, Id: id}))
+	}
+	if w := serve(bearer(token)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked token to be unauthorized, got %d", w.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/chris-langager/translationsdb/translations"
+)
+
+/*
+Form
+- what form partials are rendered with
+- Data is whatever the form is about (a project, a translation...)
+- Values and Errors are only set when a submission is sent back because it was invalid, keyed by form field and command input field
+*/
+type Form struct {
+	Data   any
+	Values map[string]string
+	Errors map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"statuses": func() []translations.TranslationStatus {
+		return translations.TranslationStatuses
+	},
+	"messageFormatError": translations.CheckMessageFormat,
+	// key ids can have characters that mean something in a url, like / or ?, so they're escaped wherever a path is built
+	"pathEscape": url.PathEscape,
+	// form wraps data for a form partial, values are pairs of field names and values, e.g. (form $ "locale" .)
+	"form": func(data any, values ...string) Form {
+		form := Form{Data: data}
+		if len(values) > 0 {
+			form.Values = map[string]string{}
+		}
+		for i := 0; i+1 < len(values); i += 2 {
+			form.Values[values[i]] = values[i+1]
+		}
+		return form
+	},
+}
+
+// formValues is the first value of every submitted field, for filling a form back in
+func formValues(r *http.Request) map[string]string {
+	values := map[string]string{}
+	for name := range r.Form {
+		values[name] = r.Form.Get(name)
+	}
+	return values
+}
+
+// parseKeyMetadata reads the key metadata fields of a form, problems that stop it from being read are added to validation
+func parseKeyMetadata(r *http.Request, validation *translations.ValidationError) translations.KeyMetadata {
+	maxLength := 0
+	if value := strings.TrimSpace(r.FormValue("max-length")); value != "" {
+		var err error
+		maxLength, err = strconv.Atoi(value)
+		if err != nil {
+			validation.Add("MaxLength", "max length has to be a whole number")
+		}
+	}
+
+	return translations.KeyMetadata{
+		Description: r.FormValue("description"),
+		Notes:       r.FormValue("notes"),
+		MaxLength:   maxLength,
+		Tags:        strings.Split(r.FormValue("tags"), ","),
+	}
+}
+
+// parseGlossaryEntry reads the glossary term fields of a form, the approved translations are the translation-<locale> fields
+func parseGlossaryEntry(r *http.Request) translations.GlossaryEntry {
+	entry := translations.GlossaryEntry{
+		Term:           r.FormValue("term"),
+		Description:    r.FormValue("description"),
+		DoNotTranslate: r.FormValue("do-not-translate") != "",
+		Translations:   map[string]string{},
+	}
+	for name := range r.PostForm {
+		if locale, ok := strings.CutPrefix(name, "translation-"); ok {
+			entry.Translations[locale] = r.PostFormValue(name)
+		}
+	}
+	return entry
+}
+
+// renderConflict tells the user someone else changed the project while they were editing it, if err is a concurrency conflict
+func renderConflict(w http.ResponseWriter, err error) bool {
+	var conflict translations.ErrConcurrencyConflict
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	http.Error(w, "Someone else changed this project at the same time, nothing was saved. Reload the page and try again.", http.StatusConflict)
+	return true
+}
+
+// renderValidationError renders the template name with form and the problems in err, if err is a validation error
+func renderValidationError(w http.ResponseWriter, err error, name string, form Form) bool {
+	var validation translations.ValidationError
+	if !errors.As(err, &validation) {
+		return false
+	}
+
+	form.Errors = validation.Fields
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	RenderHtml(w, name, form)
+	return true
+}
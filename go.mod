@@ -4,4 +4,6 @@ go 1.22.2
 
 require github.com/google/uuid v1.6.0
 
-require github.com/mattn/go-sqlite3 v1.14.22 // indirect
+require github.com/mattn/go-sqlite3 v1.14.22
+
+require golang.org/x/crypto v0.31.0
@@ -2,12 +2,18 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
 
 	"github.com/chris-langager/translationsdb/translations"
+	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -71,56 +77,288 @@ import (
 // }
 
 func main() {
-	db, _ := sql.Open("sqlite3", "./sqlite-database.db")
+	db, err := sql.Open("sqlite3", translations.SQLiteDSN("./sqlite-database.db"))
+	if err != nil {
+		panic(err)
+	}
 	defer db.Close()
 
-	eventStore := translations.NewInMemoryEventStore()
+	eventStore, err := translations.NewSQLiteEventStore(db)
+	if err != nil {
+		panic(err)
+	}
 	// projectList := translations.NewInMemoryProjectList()
 
-	createProject := translations.NewCommandPipeline(db, translations.CreateProject(), eventStore)
-	createKey := translations.NewCommandPipeline(db, translations.CreateKey(), eventStore)
-	updateTranslation := translations.NewCommandPipeline(db, translations.UpdateTranslation(), eventStore)
+	sessions := NewSessions()
+	router := newRouter(db, eventStore, sessions)
+
+	fmt.Println("listinging on port 3000...")
+	panic(http.ListenAndServe(":3000", withRequestId(authenticate(eventStore, sessions, router))))
+}
+
+// newRouter has every page and action of the app, run against db and eventStore
+func newRouter(db *sql.DB, eventStore *translations.SQLiteEventStore, sessions *Sessions) *http.ServeMux {
+	createProject := translations.NewCommandPipeline(db, eventStore, translations.CreateProject())
+	createKey := translations.NewCommandPipeline(db, eventStore, translations.CreateKey(eventStore))
+	updateTranslation := translations.NewCommandPipeline(db, eventStore, translations.UpdateTranslation(eventStore))
+	updatePluralTranslation := translations.NewCommandPipeline(db, eventStore, translations.UpdatePluralTranslation(eventStore))
+	deleteProject := translations.NewCommandPipeline(db, eventStore, translations.DeleteProject(eventStore))
+	deleteKey := translations.NewCommandPipeline(db, eventStore, translations.DeleteKey(eventStore))
+	renameKey := translations.NewCommandPipeline(db, eventStore, translations.RenameKey(eventStore))
+	moveKey := translations.NewCommandPipeline(db, eventStore, translations.MoveKey(eventStore))
+	updateKeyMetadata := translations.NewCommandPipeline(db, eventStore, translations.UpdateKeyMetadata(eventStore))
+	setKeyPlural := translations.NewCommandPipeline(db, eventStore, translations.SetKeyPlural(eventStore))
+	submitTranslation := translations.NewCommandPipeline(db, eventStore, translations.SubmitTranslation(eventStore))
+	approveTranslation := translations.NewCommandPipeline(db, eventStore, translations.ApproveTranslation(eventStore))
+	rejectTranslation := translations.NewCommandPipeline(db, eventStore, translations.RejectTranslation(eventStore))
+
+	commentThreads := translations.NewCommentThreads(eventStore)
+	addComment := translations.NewCommandPipeline(db, eventStore, translations.AddComment(eventStore, commentThreads))
+	editComment := translations.NewCommandPipeline(db, eventStore, translations.EditComment(commentThreads))
+	resolveCommentThread := translations.NewCommandPipeline(db, eventStore, translations.ResolveCommentThread(commentThreads))
+	qaResults := translations.NewQAResults(eventStore, translations.DefaultQARules)
+	setQARuleEnabled := translations.NewCommandPipeline(db, eventStore, translations.SetQARuleEnabled(eventStore, translations.DefaultQARules))
+	addGlossaryTerm := translations.NewCommandPipeline(db, eventStore, translations.AddGlossaryTerm(eventStore))
+	updateGlossaryTerm := translations.NewCommandPipeline(db, eventStore, translations.UpdateGlossaryTerm(eventStore))
+	removeGlossaryTerm := translations.NewCommandPipeline(db, eventStore, translations.RemoveGlossaryTerm(eventStore))
+	deleteTranslation := translations.NewCommandPipeline(db, eventStore, translations.DeleteTranslation(eventStore))
+	addLocale := translations.NewCommandPipeline(db, eventStore, translations.AddLocale(eventStore))
+	removeLocale := translations.NewCommandPipeline(db, eventStore, translations.RemoveLocale(eventStore))
+	setSourceLocale := translations.NewCommandPipeline(db, eventStore, translations.SetSourceLocale(eventStore))
+	setFallbacks := translations.NewCommandPipeline(db, eventStore, translations.SetFallbacks(eventStore))
+	registerUser := translations.NewCommandPipeline(db, eventStore, translations.RegisterUser(eventStore))
+	issueApiToken := translations.NewCommandPipeline(db, eventStore, translations.IssueApiToken(eventStore))
+	revokeApiToken := translations.NewCommandPipeline(db, eventStore, translations.RevokeApiToken(eventStore))
 
 	router := http.NewServeMux()
 
-	router.HandleFunc("POST /translations", func(w http.ResponseWriter, r *http.Request) {
-		projectId := r.FormValue("project-id")
-		keyId := r.FormValue("key-id")
-		id := r.FormValue("id")
-		value := r.FormValue("value")
-		err := updateTranslation(r.Context(), translations.UpdateTranslationInput{
-			ProjectId: projectId,
-			KeyId:     keyId,
-			Id:        id,
-			Value:     value,
+	router.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		RenderHtml(w, "login.html", nil)
+	})
+
+	router.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		user, err := translations.Authenticate(r.Context(), eventStore, r.FormValue("username"), r.FormValue("password"))
+		if err == translations.ErrorInvalidCredentials {
+			w.WriteHeader(http.StatusUnauthorized)
+			RenderHtml(w, "login.html", map[string]string{"Error": err.Error()})
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		err = sessions.Start(w, user.Id)
+		if err != nil {
+			panic(err)
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	router.HandleFunc("GET /register", func(w http.ResponseWriter, r *http.Request) {
+		RenderHtml(w, "register.html", Form{})
+	})
+
+	router.HandleFunc("POST /register", func(w http.ResponseWriter, r *http.Request) {
+		username := r.FormValue("username")
+		password := r.FormValue("password")
+		err := registerUser(r.Context(), translations.RegisterUserInput{
+			Username: username,
+			Password: password,
+		}, translations.AnyVersion)
+		// someone else registered in between, the username may be theirs now
+		var conflict translations.ErrConcurrencyConflict
+		if errors.As(err, &conflict) {
+			w.WriteHeader(http.StatusConflict)
+			RenderHtml(w, "register.html", Form{
+				Values: map[string]string{"username": username},
+				Errors: map[string]string{"Username": "someone else registered at the same time, try again"},
+			})
+			return
+		}
+		if renderValidationError(w, err, "register.html", Form{Values: map[string]string{"username": username}}) {
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		user, err := translations.Authenticate(r.Context(), eventStore, username, password)
+		if err != nil {
+			panic(err)
+		}
+		err = sessions.Start(w, user.Id)
+		if err != nil {
+			panic(err)
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	router.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+		sessions.End(w, r)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+
+	router.HandleFunc("GET /account", func(w http.ResponseWriter, r *http.Request) {
+		users, err := translations.GetUsers(r.Context(), eventStore)
+		if err != nil {
+			panic(err)
+		}
+
+		RenderHtml(w, "account.html", map[string]any{
+			"User": users.UsersById[translations.GetActor(r.Context())],
 		})
+	})
+
+	router.HandleFunc("POST /tokens", func(w http.ResponseWriter, r *http.Request) {
+		token, err := translations.NewApiToken()
 		if err != nil {
 			panic(err)
 		}
+		userId := translations.GetActor(r.Context())
+		err = issueApiToken(r.Context(), translations.IssueApiTokenInput{
+			UserId: userId,
+			Name:   r.FormValue("name"),
+			Token:  token,
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
 
-		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		users, err := translations.GetUsers(r.Context(), eventStore)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "account.html", map[string]any{
+				"User":   users.UsersById[userId],
+				"Errors": validation.Fields,
+			})
+			return
+		}
+
+		// the only time the token is ever shown
+		RenderHtml(w, "account.html", map[string]any{
+			"User":     users.UsersById[userId],
+			"NewToken": token,
+		})
+	})
+
+	router.HandleFunc("POST /tokens/{id}/revoke", func(w http.ResponseWriter, r *http.Request) {
+		err := revokeApiToken(r.Context(), translations.RevokeApiTokenInput{
+			UserId: translations.GetActor(r.Context()),
+			Id:     r.PathValue("id"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+	})
+
+	router.HandleFunc("POST /translations", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.FormValue("project-id")
+		keyId := r.FormValue("key-id")
+		id := r.FormValue("id")
+
+		// plural keys send a plural-<category> field per category instead of a value
+		var err error
+		if r.PostForm.Has("value") {
+			err = updateTranslation(r.Context(), translations.UpdateTranslationInput{
+				ProjectId: projectId,
+				KeyId:     keyId,
+				Id:        id,
+				Value:     r.FormValue("value"),
+			}, translations.AnyVersion)
+		} else {
+			plurals := map[translations.PluralCategory]string{}
+			for name := range r.PostForm {
+				if category, ok := strings.CutPrefix(name, "plural-"); ok {
+					plurals[translations.PluralCategory(category)] = r.PostFormValue(name)
+				}
+			}
+			err = updatePluralTranslation(r.Context(), translations.UpdatePluralTranslationInput{
+				ProjectId: projectId,
+				KeyId:     keyId,
+				Id:        id,
+				Plurals:   plurals,
+			}, translations.AnyVersion)
+		}
+		if renderConflict(w, err) {
+			return
+		}
 		if err == translations.ErrorNotFound {
 			RenderHtml(w, "fourOhFour.html", nil)
 			return
 		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
 		if err != nil {
 			panic(err)
 		}
+		translation := &translations.Translation{ProjectId: projectId, KeyId: keyId, Id: id}
+		if key, ok := project.KeysById[keyId]; ok && key.TranslationsById[id] != nil {
+			translation = key.TranslationsById[id]
+		}
+		if validation.Fields != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "translationForm.html", Form{Data: translation, Values: formValues(r), Errors: validation.Fields})
+			return
+		}
 
-		RenderHtml(w, "translationForm.html", project.KeysById[keyId].TranslationsById[id])
+		// the QA warnings on the project page reload on this
+		w.Header().Set("HX-Trigger", "translationUpdated")
+		RenderHtml(w, "translationForm.html", Form{Data: translation})
 	})
 
 	router.HandleFunc("POST /keys", func(w http.ResponseWriter, r *http.Request) {
 		id := r.FormValue("id")
 		projectId := r.FormValue("project-id")
-		err := createKey(r.Context(), translations.CreateKeyInput{
-			Id:        id,
-			ProjectId: projectId,
-		})
-		if err != nil {
+		values := map[string]string{}
+		for name := range r.PostForm {
+			if locale, ok := strings.CutPrefix(name, "value-"); ok {
+				values[locale] = r.PostFormValue(name)
+			}
+		}
+		var validation translations.ValidationError
+		metadata := parseKeyMetadata(r, &validation)
+		err := validation.Err()
+		if err == nil {
+			err = createKey(r.Context(), translations.CreateKeyInput{
+				Id:          id,
+				ProjectId:   projectId,
+				Values:      values,
+				Plural:      r.FormValue("plural") != "",
+				KeyMetadata: metadata,
+			}, translations.AnyVersion)
+		}
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil && !errors.As(err, &validation) {
 			panic(err)
 		}
 
+		// the command doesn't run when the form itself is invalid, so the project may not exist
 		project, err := translations.GetProject(r.Context(), eventStore, projectId)
 		if err == translations.ErrorNotFound {
 			RenderHtml(w, "fourOhFour.html", nil)
@@ -129,16 +367,27 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
+		if validation.Fields != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "newKeyForm.html", Form{Data: project, Values: formValues(r), Errors: validation.Fields})
+			return
+		}
 
 		RenderHtml(w, "project.html", project)
 	})
 
 	router.HandleFunc("POST /projects", func(w http.ResponseWriter, r *http.Request) {
 		err := createProject(r.Context(), translations.CreateProjectInput{
-			Name: r.FormValue("name"),
-		})
+			Name:    r.FormValue("name"),
+			Locales: strings.FieldsFunc(r.FormValue("locales"), isLocaleSeparator),
+		}, 0)
+		if renderConflict(w, err) {
+			return
+		}
+		if renderValidationError(w, err, "newProjectForm.html", Form{Values: formValues(r)}) {
+			return
+		}
 		if err != nil {
-			//TODO: validation error
 			panic(err)
 		}
 
@@ -146,12 +395,165 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		RenderHtml(w, "newProjectForm.html", nil)
+		RenderHtml(w, "newProjectForm.html", Form{})
 		RenderHtml(w, "projects.html", projectList.ProjectsById)
 		RenderHtml(w, "history.html", projectList.History)
 	})
 
-	router.HandleFunc("GET /project/{id}", func(w http.ResponseWriter, r *http.Request) {
+	router.HandleFunc("DELETE /project/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err := deleteProject(r.Context(), translations.DeleteProjectInput{
+			Id: r.PathValue("id"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		w.Header().Set("HX-Redirect", "/")
+	})
+
+	router.HandleFunc("DELETE /project/{id}/keys/{keyId}", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := deleteKey(r.Context(), translations.DeleteKeyInput{
+			ProjectId: projectId,
+			Id:        r.PathValue("keyId"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+
+		RenderHtml(w, "project.html", project)
+	})
+
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/rename", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		keyId := r.PathValue("keyId")
+		err := renameKey(r.Context(), translations.RenameKeyInput{
+			ProjectId: projectId,
+			Id:        keyId,
+			NewId:     r.FormValue("new-id"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			values := formValues(r)
+			values["key-id"] = keyId
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "renameKeyForm.html", Form{Data: project, Values: values, Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "project.html", project)
+	})
+
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/metadata", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		keyId := r.PathValue("keyId")
+		var validation translations.ValidationError
+		metadata := parseKeyMetadata(r, &validation)
+		err := validation.Err()
+		if err == nil {
+			err = updateKeyMetadata(r.Context(), translations.UpdateKeyMetadataInput{
+				ProjectId:   projectId,
+				Id:          keyId,
+				KeyMetadata: metadata,
+			}, translations.AnyVersion)
+		}
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		// the command doesn't run when the form itself is invalid, so the project or key may not exist
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		if _, ok := project.KeysById[keyId]; !ok {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if validation.Fields != nil {
+			values := formValues(r)
+			values["key-id"] = keyId
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "keyMetadataForm.html", Form{Data: project, Values: values, Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "keyMetadataForm.html", Form{Data: project, Values: map[string]string{"key-id": keyId}})
+	})
+
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/plural", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		keyId := r.PathValue("keyId")
+		err := setKeyPlural(r.Context(), translations.SetKeyPluralInput{
+			ProjectId: projectId,
+			Id:        keyId,
+			Plural:    r.FormValue("plural") != "",
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+
+		RenderHtml(w, "key.html", Form{Data: project, Values: map[string]string{"key-id": keyId}})
+	})
+
+	router.HandleFunc("GET /project/{id}/keys/{keyId}/move", func(w http.ResponseWriter, r *http.Request) {
 		project, err := translations.GetProject(r.Context(), eventStore, r.PathValue("id"))
 		if err == translations.ErrorNotFound {
 			RenderHtml(w, "fourOhFour.html", nil)
@@ -160,24 +562,724 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
+		projectList, err := translations.GetProjectList(r.Context(), eventStore)
+		if err != nil {
+			panic(err)
+		}
 
-		RenderHtml(w, "projectPage.html", project)
+		RenderHtml(w, "moveKeyForm.html", Form{Data: map[string]any{
+			"Project":  project,
+			"KeyId":    r.PathValue("keyId"),
+			"Projects": projectList.ProjectsById,
+		}})
 	})
 
-	router.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-		var projectList translations.ProjectList
-		translations.ReduceWith(r.Context(), &projectList, eventStore.NewGenerator())
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/move", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		keyId := r.PathValue("keyId")
+		err := moveKey(r.Context(), translations.MoveKeyInput{
+			ProjectId:   projectId,
+			Id:          keyId,
+			ToProjectId: r.FormValue("to-project-id"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
 
-		RenderHtml(w, "index.html", projectList)
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			projectList, err := translations.GetProjectList(r.Context(), eventStore)
+			if err != nil {
+				panic(err)
+			}
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "moveKeyForm.html", Form{
+				Data: map[string]any{
+					"Project":  project,
+					"KeyId":    keyId,
+					"Projects": projectList.ProjectsById,
+				},
+				Values: formValues(r),
+				Errors: validation.Fields,
+			})
+			return
+		}
+
+		RenderHtml(w, "project.html", project)
 	})
 
-	fmt.Println("listinging on port 3000...")
-	panic(http.ListenAndServe(":3000", router))
+	router.HandleFunc("GET /project/{id}/history", func(w http.ResponseWriter, r *http.Request) {
+		project, err := translations.GetProject(r.Context(), eventStore, r.PathValue("id"))
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		events, err := translations.GetProjectHistory(r.Context(), eventStore, project.Id)
+		if err != nil {
+			panic(err)
+		}
+		history := []string{}
+		for _, event := range events {
+			h, _ := json.MarshalIndent(event, "", "  ")
+			history = append(history, string(h))
+		}
+
+		RenderHtml(w, "history.html", history)
+	})
+	router.HandleFunc("GET /project/{id}/keys/{keyId}/history", func(w http.ResponseWriter, r *http.Request) {
+		project, err := translations.GetProject(r.Context(), eventStore, r.PathValue("id"))
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		keyId := r.PathValue("keyId")
+		if _, ok := project.KeysById[keyId]; !ok {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+
+		events, err := translations.GetKeyHistory(r.Context(), eventStore, project.Id, keyId)
+		if err != nil {
+			panic(err)
+		}
+		history := []string{}
+		for _, event := range events {
+			h, _ := json.MarshalIndent(event, "", "  ")
+			history = append(history, string(h))
+		}
+
+		RenderHtml(w, "keyHistory.html", map[string]any{
+			"Project": project,
+			"KeyId":   keyId,
+			"History": history,
+		})
+	})
+
+	router.HandleFunc("DELETE /project/{id}/keys/{keyId}/translations/{locale}", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		keyId := r.PathValue("keyId")
+		locale := r.PathValue("locale")
+		err := deleteTranslation(r.Context(), translations.DeleteTranslationInput{
+			ProjectId: projectId,
+			KeyId:     keyId,
+			Id:        locale,
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+
+		w.Header().Set("HX-Trigger", "translationUpdated")
+		RenderHtml(w, "translationForm.html", Form{Data: project.KeysById[keyId].TranslationsById[locale]})
+	})
+
+	// review handlers all run their command on the translation in the path and render it again
+	reviewHandler := func(review func(r *http.Request, projectId string, keyId string, locale string) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			projectId := r.PathValue("id")
+			keyId := r.PathValue("keyId")
+			locale := r.PathValue("locale")
+			err := review(r, projectId, keyId, locale)
+			if renderConflict(w, err) {
+				return
+			}
+			if err == translations.ErrorNotFound {
+				RenderHtml(w, "fourOhFour.html", nil)
+				return
+			}
+			var validation translations.ValidationError
+			if err != nil && !errors.As(err, &validation) {
+				panic(err)
+			}
+
+			project, err := translations.GetProject(r.Context(), eventStore, projectId)
+			if err != nil {
+				panic(err)
+			}
+			translation := project.KeysById[keyId].TranslationsById[locale]
+			if validation.Fields != nil {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				values := map[string]string{"value": translation.Value}
+				for category, value := range translation.Plurals {
+					values["plural-"+string(category)] = value
+				}
+				RenderHtml(w, "translationForm.html", Form{Data: translation, Values: values, Errors: validation.Fields})
+				return
+			}
+
+			RenderHtml(w, "translationForm.html", Form{Data: translation})
+		}
+	}
+
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/translations/{locale}/submit", reviewHandler(func(r *http.Request, projectId string, keyId string, locale string) error {
+		return submitTranslation(r.Context(), translations.SubmitTranslationInput{
+			ProjectId: projectId,
+			KeyId:     keyId,
+			Id:        locale,
+		}, translations.AnyVersion)
+	}))
+
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/translations/{locale}/approve", reviewHandler(func(r *http.Request, projectId string, keyId string, locale string) error {
+		return approveTranslation(r.Context(), translations.ApproveTranslationInput{
+			ProjectId: projectId,
+			KeyId:     keyId,
+			Id:        locale,
+		}, translations.AnyVersion)
+	}))
+
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/translations/{locale}/reject", reviewHandler(func(r *http.Request, projectId string, keyId string, locale string) error {
+		// htmx sends what was typed into hx-prompt as a header
+		reason := r.Header.Get("HX-Prompt")
+		if reason == "" {
+			reason = r.FormValue("reason")
+		}
+		return rejectTranslation(r.Context(), translations.RejectTranslationInput{
+			ProjectId: projectId,
+			KeyId:     keyId,
+			Id:        locale,
+			Reason:    reason,
+		}, translations.AnyVersion)
+	}))
+
+	// renderCommentThread renders the open thread about a translation, a key when locale is "" or the project when keyId is too,
+	// with form's values and errors
+	renderCommentThread := func(w http.ResponseWriter, r *http.Request, projectId string, keyId string, locale string, form Form) {
+		thread, err := commentThreads.OpenThread(r.Context(), projectId, keyId, locale)
+		if err != nil {
+			panic(err)
+		}
+		commentsPath := "/project/" + projectId
+		if keyId != "" {
+			commentsPath += "/keys/" + url.PathEscape(keyId)
+		}
+		if locale != "" {
+			commentsPath += "/translations/" + locale
+		}
+		form.Data = map[string]any{
+			"ProjectId":    projectId,
+			"KeyId":        keyId,
+			"Locale":       locale,
+			"CommentsPath": commentsPath + "/comments",
+			"Thread":       thread,
+			"UserId":       translations.GetActor(r.Context()),
+		}
+		if form.Errors != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		RenderHtml(w, "commentThread.html", form)
+	}
+
+	router.HandleFunc("GET /project/{id}/threads", func(w http.ResponseWriter, r *http.Request) {
+		threads, err := commentThreads.OpenThreads(r.Context(), r.PathValue("id"))
+		if err != nil {
+			panic(err)
+		}
+
+		RenderHtml(w, "openThreads.html", threads)
+	})
+
+	// the comments of a project, a key or a translation are its open thread, keyId and locale are "" when they're not in the path
+	getComments := func(w http.ResponseWriter, r *http.Request) {
+		renderCommentThread(w, r, r.PathValue("id"), r.PathValue("keyId"), r.PathValue("locale"), Form{})
+	}
+	router.HandleFunc("GET /project/{id}/comments", getComments)
+	router.HandleFunc("GET /project/{id}/keys/{keyId}/comments", getComments)
+	router.HandleFunc("GET /project/{id}/keys/{keyId}/translations/{locale}/comments", getComments)
+
+	postComment := func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		keyId := r.PathValue("keyId")
+		locale := r.PathValue("locale")
+		err := addComment(r.Context(), translations.AddCommentInput{
+			ProjectId: projectId,
+			KeyId:     keyId,
+			Locale:    locale,
+			Text:      r.FormValue("text"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		form := Form{}
+		if validation.Fields != nil {
+			form = Form{Values: formValues(r), Errors: validation.Fields}
+		}
+		renderCommentThread(w, r, projectId, keyId, locale, form)
+	}
+	router.HandleFunc("POST /project/{id}/comments", postComment)
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/comments", postComment)
+	router.HandleFunc("POST /project/{id}/keys/{keyId}/translations/{locale}/comments", postComment)
+
+	router.HandleFunc("POST /project/{id}/threads/{threadId}/comments/{commentId}", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		threadId := r.PathValue("threadId")
+		err := editComment(r.Context(), translations.EditCommentInput{
+			ProjectId: projectId,
+			ThreadId:  threadId,
+			Id:        r.PathValue("commentId"),
+			Text:      r.FormValue("text"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		thread, err := commentThreads.Thread(r.Context(), threadId)
+		if err != nil {
+			panic(err)
+		}
+		renderCommentThread(w, r, projectId, thread.KeyId, thread.Locale, Form{Errors: validation.Fields})
+	})
+
+	router.HandleFunc("POST /project/{id}/threads/{threadId}/resolve", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		threadId := r.PathValue("threadId")
+		err := resolveCommentThread(r.Context(), translations.ResolveCommentThreadInput{
+			ProjectId: projectId,
+			Id:        threadId,
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		thread, err := commentThreads.Thread(r.Context(), threadId)
+		if err != nil {
+			panic(err)
+		}
+		renderCommentThread(w, r, projectId, thread.KeyId, thread.Locale, Form{Errors: validation.Fields})
+	})
+
+	router.HandleFunc("POST /project/{id}/locales", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := addLocale(r.Context(), translations.AddLocaleInput{
+			ProjectId: projectId,
+			Locale:    r.FormValue("locale"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "newLocaleForm.html", Form{Data: project, Values: formValues(r), Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "project.html", project)
+	})
+
+	router.HandleFunc("DELETE /project/{id}/locales/{locale}", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := removeLocale(r.Context(), translations.RemoveLocaleInput{
+			ProjectId: projectId,
+			Locale:    r.PathValue("locale"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "locale.html", Form{Data: project, Values: map[string]string{"locale": r.PathValue("locale")}, Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "project.html", project)
+	})
+
+	router.HandleFunc("POST /project/{id}/source-locale", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := setSourceLocale(r.Context(), translations.SetSourceLocaleInput{
+			ProjectId: projectId,
+			Locale:    r.FormValue("locale"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "locale.html", Form{Data: project, Values: map[string]string{"locale": r.FormValue("locale")}, Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "project.html", project)
+	})
+
+	router.HandleFunc("POST /project/{id}/locales/{locale}/fallbacks", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := setFallbacks(r.Context(), translations.SetFallbacksInput{
+			ProjectId: projectId,
+			Locale:    r.PathValue("locale"),
+			Fallbacks: strings.FieldsFunc(r.FormValue("fallbacks"), isLocaleSeparator),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			values := formValues(r)
+			values["locale"] = r.PathValue("locale")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "locale.html", Form{Data: project, Values: values, Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "project.html", project)
+	})
+
+	router.HandleFunc("GET /project/{id}/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		project, err := translations.GetProject(r.Context(), eventStore, r.PathValue("id"))
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		statuses := []translations.TranslationStatus{}
+		if status, ok := translations.ParseTranslationStatus(r.FormValue("status")); ok {
+			statuses = append(statuses, status)
+		}
+
+		RenderHtml(w, "keys.html", project.Namespace(r.FormValue("namespace"), statuses...))
+	})
+
+	// renderQA renders the warnings section of the project page, or the report as JSON for API clients that ask for it
+	renderQA := func(w http.ResponseWriter, r *http.Request, projectId string, form Form) {
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err == translations.ErrorNotFound {
+			if wantsJson(r) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		report, err := qaResults.Report(r.Context(), projectId)
+		if err != nil {
+			panic(err)
+		}
+
+		if wantsJson(r) {
+			RenderJson(w, report)
+			return
+		}
+		form.Data = qaSection{Project: project, Report: report, Rules: translations.DefaultQARules.All()}
+		if form.Errors != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		RenderHtml(w, "qaReport.html", form)
+	}
+
+	router.HandleFunc("GET /project/{id}/qa", func(w http.ResponseWriter, r *http.Request) {
+		renderQA(w, r, r.PathValue("id"), Form{})
+	})
+
+	router.HandleFunc("POST /project/{id}/qa/rules/{rule}", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := setQARuleEnabled(r.Context(), translations.SetQARuleEnabledInput{
+			ProjectId: projectId,
+			Rule:      r.PathValue("rule"),
+			Enabled:   r.FormValue("enabled") != "",
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		renderQA(w, r, projectId, Form{Errors: validation.Fields})
+	})
+
+	router.HandleFunc("GET /project/{id}/glossary", func(w http.ResponseWriter, r *http.Request) {
+		project, err := translations.GetProject(r.Context(), eventStore, r.PathValue("id"))
+		if err == translations.ErrorNotFound {
+			if wantsJson(r) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		if wantsJson(r) {
+			RenderJson(w, project.Glossary())
+			return
+		}
+		RenderHtml(w, "glossaryPage.html", project)
+	})
+
+	router.HandleFunc("POST /project/{id}/glossary", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := addGlossaryTerm(r.Context(), translations.AddGlossaryTermInput{
+			ProjectId:     projectId,
+			GlossaryEntry: parseGlossaryEntry(r),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "newGlossaryTermForm.html", Form{Data: project, Values: formValues(r), Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "glossary.html", project)
+	})
+
+	router.HandleFunc("POST /project/{id}/glossary/{termId}", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		termId := r.PathValue("termId")
+		err := updateGlossaryTerm(r.Context(), translations.UpdateGlossaryTermInput{
+			ProjectId:     projectId,
+			Id:            termId,
+			GlossaryEntry: parseGlossaryEntry(r),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		var validation translations.ValidationError
+		if err != nil && !errors.As(err, &validation) {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+		if validation.Fields != nil {
+			values := formValues(r)
+			values["term-id"] = termId
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			RenderHtml(w, "glossaryTerm.html", Form{Data: project, Values: values, Errors: validation.Fields})
+			return
+		}
+
+		RenderHtml(w, "glossaryTerm.html", Form{Data: project, Values: map[string]string{"term-id": termId}})
+	})
+
+	router.HandleFunc("DELETE /project/{id}/glossary/{termId}", func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("id")
+		err := removeGlossaryTerm(r.Context(), translations.RemoveGlossaryTermInput{
+			ProjectId: projectId,
+			Id:        r.PathValue("termId"),
+		}, translations.AnyVersion)
+		if renderConflict(w, err) {
+			return
+		}
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		project, err := translations.GetProject(r.Context(), eventStore, projectId)
+		if err != nil {
+			panic(err)
+		}
+
+		RenderHtml(w, "glossary.html", project)
+	})
+
+	router.HandleFunc("GET /project/{id}", func(w http.ResponseWriter, r *http.Request) {
+		project, err := translations.GetProject(r.Context(), eventStore, r.PathValue("id"))
+		if err == translations.ErrorNotFound {
+			RenderHtml(w, "fourOhFour.html", nil)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		RenderHtml(w, "projectPage.html", project)
+	})
+
+	router.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		projectList, err := translations.GetProjectList(r.Context(), eventStore)
+		if err != nil {
+			panic(err)
+		}
+
+		RenderHtml(w, "index.html", projectList)
+	})
+
+	return router
+}
+
+// locales can be typed in separated by commas and/or spaces
+func isLocaleSeparator(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+}
+
+// withRequestId correlates every event a request creates with its X-Request-Id, generating one if the client didn't send it
+func withRequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-Id")
+		if requestId == "" {
+			requestId = uuid.NewString()
+		}
+		w.Header().Set("X-Request-Id", requestId)
+
+		next.ServeHTTP(w, r.WithContext(translations.WithRequestId(r.Context(), requestId)))
+	})
+}
+
+// qaSection is what qaReport.html is rendered with, as the Data of a Form
+type qaSection struct {
+	Project *translations.Project
+	Report  translations.QAReport
+	Rules   []translations.QARule
+}
+
+// wantsJson reports whether the client asked for JSON rather than HTML
+func wantsJson(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func RenderJson(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		panic(err)
+	}
 }
 
 // TODO: split behavior on local or server
 func RenderHtml(wr io.Writer, name string, data any) {
-	t, err := template.ParseGlob("**/*.html")
+	t, err := template.New("").Funcs(templateFuncs).ParseGlob("**/*.html")
 	if err != nil {
 		panic(err)
 	}
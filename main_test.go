@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chris-langager/translationsdb/translations"
+)
+
+func newTestSQLiteEventStore(t *testing.T) (*sql.DB, *translations.SQLiteEventStore) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", translations.SQLiteDSN(filepath.Join(t.TempDir(), "sqlite-database.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	eventStore, err := translations.NewSQLiteEventStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, eventStore
+}
+
+func TestKeyRoutesEscapeKeyIds(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+
+	createProject := translations.NewCommandPipeline(db, eventStore, translations.CreateProject())
+	err := createProject(ctx, translations.CreateProjectInput{Name: "checkout", Locales: []string{"en"}}, translations.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectList, err := translations.GetProjectList(ctx, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectId := ""
+	for id := range projectList.ProjectsById {
+		projectId = id
+	}
+
+	// every character here means something in a url
+	keyId := "checkout/pay?x#y%z"
+	createKey := translations.NewCommandPipeline(db, eventStore, translations.CreateKey(eventStore))
+	err = createKey(ctx, translations.CreateKeyInput{ProjectId: projectId, Id: keyId, Values: map[string]string{"en": "Pay"}}, translations.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newRouter(db, eventStore, NewSessions())
+	serve := func(method string, path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d", method, path, w.Code)
+		}
+		return w.Body.String()
+	}
+
+	keyPath := "/project/" + projectId + "/keys/" + url.PathEscape(keyId)
+	if body := serve("GET", "/project/"+projectId); !strings.Contains(body, `hx-delete="`+keyPath+`"`) {
+		t.Errorf("expected the page to link to %s, got %s", keyPath, body)
+	}
+	if body := serve("GET", keyPath+"/history"); !strings.Contains(body, template.HTMLEscapeString(`"Value": "Pay"`)) {
+		t.Errorf("expected the key's history, got %s", body)
+	}
+
+	serve("DELETE", keyPath)
+	project, err := translations.GetProject(ctx, eventStore, projectId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := project.KeysById[keyId]; ok {
+		t.Errorf("expected %s to be deleted", keyId)
+	}
+}
+
+func TestInvalidKeyFormsForMissingProjects(t *testing.T) {
+	db, eventStore := newTestSQLiteEventStore(t)
+	router := newRouter(db, eventStore, NewSessions())
+
+	// max-length isn't a number, so the form is invalid before any command checks the project
+	for _, path := range []string{"/keys", "/project/missing/keys/header_1/metadata"} {
+		form := url.Values{"project-id": {"missing"}, "id": {"header_1"}, "max-length": {"many"}}
+		r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if !strings.Contains(w.Body.String(), "<h2>404</h2>") {
+			t.Errorf("POST %s: expected the 404 page, got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCommentThreadRoutes(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+	err := translations.NewCommandPipeline(db, eventStore, translations.CreateProject())(ctx, translations.CreateProjectInput{Name: "checkout", Locales: []string{"en"}}, translations.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectList, err := translations.GetProjectList(ctx, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectId := ""
+	for id := range projectList.ProjectsById {
+		projectId = id
+	}
+	err = translations.NewCommandPipeline(db, eventStore, translations.CreateKey(eventStore))(ctx, translations.CreateKeyInput{ProjectId: projectId, Id: "checkout/pay"}, translations.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newRouter(db, eventStore, NewSessions())
+	for path, text := range map[string]string{
+		"/project/" + projectId + "/comments":                                     "About the project",
+		"/project/" + projectId + "/keys/checkout%2Fpay/comments":                 "About the key",
+		"/project/" + projectId + "/keys/checkout%2Fpay/translations/en/comments": "About the translation",
+	} {
+		form := url.Values{"text": {text}}
+		r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), text) || !strings.Contains(w.Body.String(), `hx-post="`+path+`"`) {
+			t.Errorf("POST %s: expected the thread with %q that posts back to it, got %d %s", path, text, w.Code, w.Body.String())
+		}
+
+		// each thread only has its own comment
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if strings.Count(w.Body.String(), `<p>About the`) != 1 {
+			t.Errorf("GET %s: expected only %q, got %s", path, text, w.Body.String())
+		}
+	}
+}
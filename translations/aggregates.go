@@ -3,6 +3,9 @@ package translations
 import (
 	"context"
 	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -46,23 +49,53 @@ type Project struct {
 	Locales     []string
 	KeysById    map[string]*Key
 
-	History []string
+	// the locale everything is translated from, and the last fallback for every other locale
+	SourceLocale string
+	// configured fallbacks, locales without one fall back to their parent tags, see FallbackChain
+	FallbacksByLocale map[string][]string
+	// names of the QA rules the project doesn't run, see QARuleEnabled
+	DisabledQARules []string
+	GlossaryById    map[string]*GlossaryTerm
+
+	// deleted projects keep reducing so their version stays right, GetProject treats them as not found
+	Deleted bool
+
+	// number of events reduced, used as the expected version when writing back to this project
+	Version int
+	// position of the last event reduced
+	Position int64
 }
 
 type Key struct {
 	Id          string
 	DateCreated time.Time
 	DateUpdated time.Time
+	// plural keys have a value per plural category in every translation, see Translation.Plurals
+	Plural bool
+	KeyMetadata
 
 	TranslationsById map[string]*Translation
 }
 
+type GlossaryTerm struct {
+	Id          string
+	DateCreated time.Time
+	DateUpdated time.Time
+	GlossaryEntry
+}
+
 type Translation struct {
 	Id          string
 	DateCreated time.Time
 	DateUpdated time.Time
 
 	Value string
+	// the value for each plural category when the key is plural, Value is then the other category
+	Plural  bool
+	Plurals map[PluralCategory]string
+	Status  TranslationStatus
+	// why it was rejected, cleared when it moves on
+	RejectionReason string
 
 	ProjectId string
 	KeyId     string
@@ -71,24 +104,75 @@ type Translation struct {
 func (o *Project) Reduce(event Event) {
 	switch e := event.(type) {
 	case ProjectCreated:
+		locales := append([]string{}, e.Locales...)
+		sourceLocale := ""
+		if len(locales) > 0 {
+			sourceLocale = locales[0]
+		}
 		*o = Project{
-			Id:          e.Id,
-			Name:        e.Name,
-			DateCreated: e.Timestamp,
-			DateUpdated: e.Timestamp,
-			Locales:     []string{"es", "en"},
-			KeysById:    map[string]*Key{},
+			Id:                e.Id,
+			Name:              e.Name,
+			DateCreated:       e.Timestamp,
+			DateUpdated:       e.Timestamp,
+			Locales:           locales,
+			KeysById:          map[string]*Key{},
+			SourceLocale:      sourceLocale,
+			FallbacksByLocale: map[string][]string{},
+			GlossaryById:      map[string]*GlossaryTerm{},
 		}
 	case ProjectUpdated:
 		o.Name = e.Name
 		o.DateUpdated = e.Timestamp
 	case ProjectDeleted:
-		o = nil
+		o.Deleted = true
+	case LocaleAdded:
+		if Contains(o.Locales, e.Locale) {
+			break
+		}
+		o.Locales = append(o.Locales, e.Locale)
+		for _, key := range o.KeysById {
+			key.TranslationsById[e.Locale] = &Translation{
+				ProjectId:   o.Id,
+				KeyId:       key.Id,
+				Id:          e.Locale,
+				DateCreated: e.Timestamp,
+				DateUpdated: e.Timestamp,
+				Plural:      key.Plural,
+				Plurals:     emptyPlurals(key.Plural),
+				Status:      StatusUntranslated,
+			}
+		}
+	case LocaleRemoved:
+		isRemoved := func(locale string) bool { return locale == e.Locale }
+		o.Locales = slices.DeleteFunc(o.Locales, isRemoved)
+		for _, key := range o.KeysById {
+			delete(key.TranslationsById, e.Locale)
+		}
+		delete(o.FallbacksByLocale, e.Locale)
+		for locale, fallbacks := range o.FallbacksByLocale {
+			o.FallbacksByLocale[locale] = slices.DeleteFunc(fallbacks, isRemoved)
+		}
+		if o.SourceLocale == e.Locale {
+			o.SourceLocale = ""
+			if len(o.Locales) > 0 {
+				o.SourceLocale = o.Locales[0]
+			}
+		}
+	case SourceLocaleChanged:
+		o.SourceLocale = e.Locale
+	case FallbacksChanged:
+		if len(e.Fallbacks) == 0 {
+			delete(o.FallbacksByLocale, e.Locale)
+			break
+		}
+		o.FallbacksByLocale[e.Locale] = append([]string{}, e.Fallbacks...)
 	case KeyCreated:
 		key := &Key{
 			Id:               e.Id,
 			DateCreated:      e.Timestamp,
 			DateUpdated:      e.Timestamp,
+			Plural:           e.Plural,
+			KeyMetadata:      e.KeyMetadata,
 			TranslationsById: map[string]*Translation{},
 		}
 		for _, locale := range o.Locales {
@@ -98,11 +182,84 @@ func (o *Project) Reduce(event Event) {
 				Id:          locale,
 				DateCreated: e.Timestamp,
 				DateUpdated: e.Timestamp,
+				Plural:      e.Plural,
+				Plurals:     emptyPlurals(e.Plural),
+				Status:      StatusUntranslated,
 			}
 		}
 		o.KeysById[e.Id] = key
 	case KeyDeleted:
 		delete(o.KeysById, e.Id)
+	case KeyRenamed:
+		key, ok := o.KeysById[e.Id]
+		if !ok {
+			break
+		}
+		delete(o.KeysById, e.Id)
+		key.Id = e.NewId
+		key.DateUpdated = e.Timestamp
+		for _, translation := range key.TranslationsById {
+			translation.KeyId = e.NewId
+		}
+		o.KeysById[e.NewId] = key
+	case KeyPluralChanged:
+		key, ok := o.KeysById[e.Id]
+		if !ok || key.Plural == e.Plural {
+			break
+		}
+		key.Plural = e.Plural
+		key.DateUpdated = e.Timestamp
+		for _, translation := range key.TranslationsById {
+			translation.Plural = e.Plural
+			translation.Plurals = emptyPlurals(e.Plural)
+			if e.Plural && translation.Value != "" {
+				translation.Plurals[PluralOther] = translation.Value
+			}
+		}
+	case KeyMetadataUpdated:
+		key, ok := o.KeysById[e.Id]
+		if !ok {
+			break
+		}
+		key.KeyMetadata = e.KeyMetadata
+		key.DateUpdated = e.Timestamp
+	case KeyMoved:
+		if e.FromProjectId == o.Id {
+			delete(o.KeysById, e.Id)
+		}
+		if e.ToProjectId != o.Id {
+			break
+		}
+		key := &Key{
+			Id:               e.Id,
+			DateCreated:      e.DateCreated,
+			DateUpdated:      e.Timestamp,
+			Plural:           e.Plural,
+			KeyMetadata:      e.KeyMetadata,
+			TranslationsById: map[string]*Translation{},
+		}
+		for _, locale := range o.Locales {
+			plurals := emptyPlurals(e.Plural)
+			for category, value := range e.Plurals[locale] {
+				plurals[category] = value
+			}
+			translation := &Translation{
+				ProjectId:   o.Id,
+				KeyId:       e.Id,
+				Id:          locale,
+				DateCreated: e.Timestamp,
+				DateUpdated: e.Timestamp,
+				Value:       e.Values[locale],
+				Plural:      e.Plural,
+				Plurals:     plurals,
+				Status:      StatusUntranslated,
+			}
+			if status, ok := e.Statuses[locale]; ok {
+				translation.Status = status
+			}
+			key.TranslationsById[locale] = translation
+		}
+		o.KeysById[e.Id] = key
 	// case TranslationCreated:
 	// 	key, ok := o.KeysById[e.KeyId]
 	// 	if !ok {
@@ -120,26 +277,203 @@ func (o *Project) Reduce(event Event) {
 		if !ok {
 			break
 		}
+		translation, ok := key.TranslationsById[e.Id]
+		if !ok {
+			break
+		}
+		key.DateUpdated = e.Timestamp
+		translation.DateUpdated = e.Timestamp
+		plurals := translation.Plurals
+		if key.Plural {
+			plurals = maps.Clone(plurals)
+			plurals[PluralOther] = e.Value
+		}
+		o.setValue(key, translation, e.Value, plurals)
+	case PluralTranslationUpdated:
+		key, ok := o.KeysById[e.KeyId]
+		if !ok || !key.Plural {
+			break
+		}
+		translation, ok := key.TranslationsById[e.Id]
+		if !ok {
+			break
+		}
 		key.DateUpdated = e.Timestamp
-		key.TranslationsById[e.Id].DateUpdated = e.Timestamp
-		key.TranslationsById[e.Id].Value = e.Value
+		translation.DateUpdated = e.Timestamp
+		plurals := emptyPlurals(true)
+		for category, value := range e.Plurals {
+			plurals[category] = value
+		}
+		o.setValue(key, translation, plurals[PluralOther], plurals)
 	case TranslationDeleted:
+		// every key has a translation for every locale, so deleting one only clears its value
 		key, ok := o.KeysById[e.KeyId]
 		if !ok {
 			break
 		}
-		delete(key.TranslationsById, e.Id)
+		translation, ok := key.TranslationsById[e.Id]
+		if !ok {
+			break
+		}
+		key.DateUpdated = e.Timestamp
+		translation.DateUpdated = e.Timestamp
+		translation.Value = ""
+		translation.Plurals = emptyPlurals(translation.Plural)
+		translation.Status = StatusUntranslated
+		translation.RejectionReason = ""
+	case QARuleEnabled:
+		o.DisabledQARules = slices.DeleteFunc(o.DisabledQARules, func(rule string) bool { return rule == e.Rule })
+	case QARuleDisabled:
+		if !Contains(o.DisabledQARules, e.Rule) {
+			o.DisabledQARules = append(o.DisabledQARules, e.Rule)
+		}
+	case GlossaryTermAdded:
+		o.GlossaryById[e.Id] = &GlossaryTerm{
+			Id:            e.Id,
+			DateCreated:   e.Timestamp,
+			DateUpdated:   e.Timestamp,
+			GlossaryEntry: e.GlossaryEntry,
+		}
+	case GlossaryTermUpdated:
+		term, ok := o.GlossaryById[e.Id]
+		if !ok {
+			break
+		}
+		term.GlossaryEntry = e.GlossaryEntry
+		term.DateUpdated = e.Timestamp
+	case GlossaryTermRemoved:
+		delete(o.GlossaryById, e.Id)
+	case TranslationSubmitted:
+		if translation := o.translation(e.KeyId, e.Id); translation != nil {
+			translation.DateUpdated = e.Timestamp
+			translation.Status = StatusNeedsReview
+			translation.RejectionReason = ""
+		}
+	case TranslationApproved:
+		if translation := o.translation(e.KeyId, e.Id); translation != nil {
+			translation.DateUpdated = e.Timestamp
+			translation.Status = StatusApproved
+			translation.RejectionReason = ""
+		}
+	case TranslationRejected:
+		if translation := o.translation(e.KeyId, e.Id); translation != nil {
+			translation.DateUpdated = e.Timestamp
+			translation.Status = StatusRejected
+			translation.RejectionReason = e.Reason
+		}
 	}
 
 	o.DateUpdated = event.GetTimestamp()
+	o.Version++
+	o.Position = event.GetPosition()
+}
+
+// setValue gives a translation a new value, and plural variants for plural keys,
+// changing the source value sends the other translations back to review
+func (o *Project) setValue(key *Key, translation *Translation, value string, plurals map[PluralCategory]string) {
+	if translation.Value == value && maps.Equal(translation.Plurals, plurals) {
+		return
+	}
+	hadValue := translation.hasValue()
+	translation.Value = value
+	translation.Plurals = plurals
+	translation.Status = translation.valueStatus()
+	translation.RejectionReason = ""
+
+	// translations were made from the old source value, giving the source its first value doesn't count
+	if translation.Id != o.SourceLocale || !hadValue {
+		return
+	}
+	for locale, other := range key.TranslationsById {
+		if locale != translation.Id && other.hasValue() {
+			other.Status = StatusNeedsReview
+			other.RejectionReason = ""
+		}
+	}
+}
+
+// emptyPlurals is what a translation starts with for its plural variants
+func emptyPlurals(plural bool) map[PluralCategory]string {
+	if !plural {
+		return nil
+	}
+	return map[PluralCategory]string{}
+}
+
+// translation returns the translation of keyId in locale, nil if there isn't one
+func (o *Project) translation(keyId string, locale string) *Translation {
+	key, ok := o.KeysById[keyId]
+	if !ok {
+		return nil
+	}
+	return key.TranslationsById[locale]
+}
 
-	h, _ := json.MarshalIndent(event, "", "  ")
-	o.History = append([]string{string(h)}, o.History...)
+// eventList collects every event it's reduced with
+type eventList []Event
+
+func (o *eventList) Reduce(event Event) {
+	*o = append(*o, event)
+}
+
+// projectListEventTypes are the only events ProjectList reacts to, read it with EventTypes(projectListEventTypes...)
+var projectListEventTypes = []string{
+	TypeName(ProjectCreated{}),
+	TypeName(ProjectUpdated{}),
+	TypeName(ProjectDeleted{}),
+}
+
+// FallbackChain is the locales looked at, in order, when locale has no value: its configured fallbacks,
+// or its parent tags that are project locales (pt-BR -> pt), and finally the source locale
+func (o *Project) FallbackChain(locale string) []string {
+	chain := []string{}
+	fallbacks, ok := o.FallbacksByLocale[locale]
+	if !ok {
+		for parent := locale; strings.Contains(parent, "-"); {
+			parent = parent[:strings.LastIndex(parent, "-")]
+			if Contains(o.Locales, parent) {
+				fallbacks = append(fallbacks, parent)
+			}
+		}
+	}
+	for _, fallback := range fallbacks {
+		if fallback != locale && !Contains(chain, fallback) {
+			chain = append(chain, fallback)
+		}
+	}
+	if o.SourceLocale != "" && o.SourceLocale != locale && !Contains(chain, o.SourceLocale) {
+		chain = append(chain, o.SourceLocale)
+	}
+	return chain
+}
+
+type Resolution struct {
+	Value string
+	// the locale Value came from, "" when neither locale nor any of its fallbacks has a value
+	Locale string
+}
+
+// Resolve returns the effective value of a key in locale, following locale's FallbackChain when it's empty
+func (o *Project) Resolve(keyId string, locale string) Resolution {
+	key, ok := o.KeysById[keyId]
+	if !ok {
+		return Resolution{}
+	}
+
+	for _, l := range append([]string{locale}, o.FallbackChain(locale)...) {
+		translation, ok := key.TranslationsById[l]
+		if ok && translation.Value != "" {
+			return Resolution{Value: translation.Value, Locale: l}
+		}
+	}
+	return Resolution{}
 }
 
 type ProjectList struct {
 	ProjectsById map[string]*Project
-	History      []string
+	// position of the last event reduced, read on from here with AfterPosition
+	Position int64
+	History  []string
 }
 
 func (o *ProjectList) Reduce(event Event) {
@@ -158,6 +492,89 @@ func (o *ProjectList) Reduce(event Event) {
 		delete(o.ProjectsById, e.Id)
 	}
 
+	o.Position = event.GetPosition()
+
 	s, _ := json.MarshalIndent(event, "", "  ")
 	o.History = append(o.History, string(s))
 }
+
+var usersEventTypes = []string{
+	TypeName(UserRegistered{}),
+	TypeName(ApiTokenIssued{}),
+	TypeName(ApiTokenRevoked{}),
+}
+
+type User struct {
+	Id           string
+	Username     string
+	PasswordHash string
+	DateCreated  time.Time
+
+	ApiTokensById map[string]*ApiToken
+}
+
+type ApiToken struct {
+	Id          string
+	Name        string
+	TokenHash   string
+	DateCreated time.Time
+}
+
+// usersAggregateId is the stream every registration is written to, so two of them can't both take a username
+const usersAggregateId = "users"
+
+type Users struct {
+	UsersById       map[string]*User
+	UsersByUsername map[string]*User
+	UsersByApiToken map[string]*User // keyed by token hash
+	// of the usersAggregateId stream, users registered before it existed are in a stream of their own
+	Version int
+}
+
+func (o *Users) Reduce(event Event) {
+	if o.UsersById == nil {
+		o.UsersById = map[string]*User{}
+		o.UsersByUsername = map[string]*User{}
+		o.UsersByApiToken = map[string]*User{}
+	}
+
+	if event.GetAggregateId() == usersAggregateId {
+		o.Version = event.GetVersion()
+	}
+
+	switch e := event.(type) {
+	case UserRegistered:
+		user := &User{
+			Id:            e.Id,
+			Username:      e.Username,
+			PasswordHash:  e.PasswordHash,
+			DateCreated:   e.Timestamp,
+			ApiTokensById: map[string]*ApiToken{},
+		}
+		o.UsersById[e.Id] = user
+		o.UsersByUsername[e.Username] = user
+	case ApiTokenIssued:
+		user, ok := o.UsersById[e.UserId]
+		if !ok {
+			break
+		}
+		user.ApiTokensById[e.Id] = &ApiToken{
+			Id:          e.Id,
+			Name:        e.Name,
+			TokenHash:   e.TokenHash,
+			DateCreated: e.Timestamp,
+		}
+		o.UsersByApiToken[e.TokenHash] = user
+	case ApiTokenRevoked:
+		user, ok := o.UsersById[e.UserId]
+		if !ok {
+			break
+		}
+		token, ok := user.ApiTokensById[e.Id]
+		if !ok {
+			break
+		}
+		delete(o.UsersByApiToken, token.TokenHash)
+		delete(user.ApiTokensById, e.Id)
+	}
+}
@@ -0,0 +1,45 @@
+package translations
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProjectResolve(t *testing.T) {
+	ctx := context.Background()
+	var project Project
+	for _, event := range []Event{
+		ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Locales: []string{"en", "pt", "pt-BR", "es"}},
+		KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: "greeting"},
+		TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: "greeting", Id: "en", Value: "Hello"},
+		TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: "greeting", Id: "pt", Value: "Olá"},
+	} {
+		project.Reduce(event)
+	}
+
+	for locale, expected := range map[string]Resolution{
+		"pt-BR": {Value: "Olá", Locale: "pt"},   // parent tag
+		"es":    {Value: "Hello", Locale: "en"}, // source locale
+		"pt":    {Value: "Olá", Locale: "pt"},   // its own value
+		"fr":    {Value: "Hello", Locale: "en"}, // not a project locale, still falls back to the source
+	} {
+		if resolution := project.Resolve("greeting", locale); resolution != expected {
+			t.Errorf("%s: expected %+v, got %+v", locale, expected, resolution)
+		}
+	}
+
+	project.Reduce(FallbacksChanged{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Locale: "es", Fallbacks: []string{"pt"}})
+	if resolution := project.Resolve("greeting", "es"); resolution.Locale != "pt" {
+		t.Errorf("expected es to fall back to pt first, got %+v", resolution)
+	}
+
+	project.Reduce(SourceLocaleChanged{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Locale: "pt"})
+	project.Reduce(FallbacksChanged{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Locale: "es"})
+	if resolution := project.Resolve("greeting", "es"); resolution.Locale != "pt" {
+		t.Errorf("expected es to fall back to the new source locale, got %+v", resolution)
+	}
+
+	if resolution := project.Resolve("missing", "en"); resolution != (Resolution{}) {
+		t.Errorf("expected nothing for a missing key, got %+v", resolution)
+	}
+}
@@ -4,6 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -12,36 +19,79 @@ import (
 Commands
 - take input, return event(s) or an error
 - can read from whatever dependencies they want
+- invalid input is reported as a ValidationError, ErrorNotFound when what the input refers to doesn't exist
 */
 
-type Command[T any] func(context.Context, T) (Event, error)
+type Command[T any] func(context.Context, T) ([]Event, error)
 
 type ReadModel interface {
 	Handle(ctx context.Context, tx *sql.Tx, event Event) error
 }
 
-func NewCommandPipeline[T any](db *sql.DB, command Command[T], readModels ...ReadModel) func(context.Context, T) error {
-	return func(ctx context.Context, t T) error {
-		event, err := command(ctx, t)
+/*
+NewCommandPipeline
+- runs the command, then writes all of its events to the event store and every read model in a single transaction
+- expectedVersion is the version of the first event's aggregate the caller based its input on, or AnyVersion
+- with AnyVersion the events are checked against the version the command read with GetProject instead,
+so a change written in between fails with ErrConcurrencyConflict rather than being overwritten
+- events for other aggregates, like the destination project of a MoveKey, are always checked against the version the command read
+- once committed, the projects written to are snapshotted when they're due, see saveProjectSnapshotIfDue
+*/
+func NewCommandPipeline[T any](db *sql.DB, eventStore EventStore, command Command[T], readModels ...ReadModel) func(context.Context, T, int) error {
+	return func(ctx context.Context, t T, expectedVersion int) error {
+		// everything one command does is correlated, even without a request to correlate it with
+		if GetCorrelationId(ctx) == "" {
+			ctx = WithCorrelationId(ctx, uuid.NewString())
+		}
+
+		commandCtx, versions := withReadVersions(ctx)
+		events, err := command(commandCtx, t)
 		if err != nil {
 			return err
 		}
+		if len(events) == 0 {
+			return nil
+		}
 
 		tx, err := db.BeginTx(ctx, &sql.TxOptions{})
 		if err != nil {
 			return err
 		}
-		for _, readModel := range readModels {
-			err = readModel.Handle(ctx, tx, event)
+		// a command can write to more than one aggregate, like MoveKey, each is checked once against the version it was read at
+		groups := groupByAggregate(events)
+		for i, aggregateEvents := range groups {
+			aggregateVersion := versions.get(aggregateEvents[0].GetAggregateId())
+			if i == 0 && expectedVersion != AnyVersion {
+				aggregateVersion = expectedVersion
+			}
+			err = eventStore.Write(ctx, tx, aggregateVersion, aggregateEvents...)
 			if err != nil {
 				mustRollback(tx)
 				return err
 			}
 		}
+		// read models see the events in the order they were written
+		events = slices.Concat(groups...)
+		for _, event := range events {
+			for _, readModel := range readModels {
+				err = readModel.Handle(ctx, tx, event)
+				if err != nil {
+					mustRollback(tx)
+					return err
+				}
+			}
+		}
 		err = tx.Commit()
 		if err != nil {
 			return err
 		}
+		notifyCommitted(eventStore)
+
+		// a snapshot is only a shortcut, the next write to the aggregate tries again, so failing to take one doesn't fail
+		// a command that's already committed
+		for _, aggregateEvents := range groups {
+			_ = saveProjectSnapshotIfDue(ctx, eventStore, aggregateEvents[0].GetAggregateId())
+		}
 		return nil
 	}
 }
@@ -55,15 +105,35 @@ func mustRollback(tx *sql.Tx) {
 
 type CreateProjectInput struct {
 	Name string
+	// BCP 47 language tags, DefaultLocales when empty
+	Locales []string
 }
 
-func CreateProject() func(ctx context.Context, input CreateProjectInput) (Event, error) {
-	return func(ctx context.Context, input CreateProjectInput) (Event, error) {
+func CreateProject() func(ctx context.Context, input CreateProjectInput) ([]Event, error) {
+	return func(ctx context.Context, input CreateProjectInput) ([]Event, error) {
+		var validation ValidationError
+		if strings.TrimSpace(input.Name) == "" {
+			validation.Add("Name", "name is required")
+		}
+		locales, err := CanonicalizeLocales(input.Locales)
+		if err != nil {
+			validation.Add("Locales", err.Error())
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+		if len(locales) == 0 {
+			locales = append(locales, DefaultLocales...)
+		}
+
 		id := uuid.NewString()
-		return ProjectCreated{
-			EventBase: NewEventBase(ctx, id),
-			Id:        id,
-			Name:      input.Name,
+		return []Event{
+			ProjectCreated{
+				EventBase: NewEventBase(ctx, id),
+				Id:        id,
+				Name:      strings.TrimSpace(input.Name),
+				Locales:   locales,
+			},
 		}, nil
 	}
 }
@@ -73,17 +143,186 @@ type UpdateProjectInput struct {
 	Name string
 }
 
-func UpdateProject(eventStore EventStore) func(ctx context.Context, input UpdateProjectInput) (Event, error) {
-	return func(ctx context.Context, input UpdateProjectInput) (Event, error) {
+func UpdateProject(eventStore EventStore) func(ctx context.Context, input UpdateProjectInput) ([]Event, error) {
+	return func(ctx context.Context, input UpdateProjectInput) ([]Event, error) {
+		_, err := GetProject(ctx, eventStore, input.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		if strings.TrimSpace(input.Name) == "" {
+			validation.Add("Name", "name is required")
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			ProjectUpdated{
+				EventBase: NewEventBase(ctx, input.Id),
+				Id:        input.Id,
+				Name:      strings.TrimSpace(input.Name),
+			},
+		}, nil
+	}
+}
+
+type DeleteProjectInput struct {
+	Id string
+}
+
+func DeleteProject(eventStore EventStore) func(ctx context.Context, input DeleteProjectInput) ([]Event, error) {
+	return func(ctx context.Context, input DeleteProjectInput) ([]Event, error) {
 		_, err := GetProject(ctx, eventStore, input.Id)
 		if err != nil {
 			return nil, err
 		}
 
-		return ProjectUpdated{
-			EventBase: NewEventBase(ctx, input.Id),
-			Id:        input.Id,
-			Name:      input.Name,
+		return []Event{
+			ProjectDeleted{
+				EventBase: NewEventBase(ctx, input.Id),
+				Id:        input.Id,
+			},
+		}, nil
+	}
+}
+
+type AddLocaleInput struct {
+	ProjectId string
+	Locale    string
+}
+
+func AddLocale(eventStore EventStore) func(ctx context.Context, input AddLocaleInput) ([]Event, error) {
+	return func(ctx context.Context, input AddLocaleInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		locale, err := CanonicalizeLocale(input.Locale)
+		if err != nil {
+			validation.Add("Locale", err.Error())
+		} else if Contains(project.Locales, locale) {
+			validation.Add("Locale", fmt.Sprintf("%s has already been added", locale))
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			LocaleAdded{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Locale:    locale,
+			},
+		}, nil
+	}
+}
+
+type RemoveLocaleInput struct {
+	ProjectId string
+	Locale    string
+}
+
+func RemoveLocale(eventStore EventStore) func(ctx context.Context, input RemoveLocaleInput) ([]Event, error) {
+	return func(ctx context.Context, input RemoveLocaleInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		if !Contains(project.Locales, input.Locale) {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		if len(project.Locales) == 1 {
+			validation.Add("Locale", "a project needs at least one locale")
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			LocaleRemoved{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Locale:    input.Locale,
+			},
+		}, nil
+	}
+}
+
+type SetSourceLocaleInput struct {
+	ProjectId string
+	Locale    string
+}
+
+func SetSourceLocale(eventStore EventStore) func(ctx context.Context, input SetSourceLocaleInput) ([]Event, error) {
+	return func(ctx context.Context, input SetSourceLocaleInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		var validation ValidationError
+		if !Contains(project.Locales, input.Locale) {
+			validation.Add("Locale", fmt.Sprintf("%s is not one of the project's locales", input.Locale))
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			SourceLocaleChanged{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Locale:    input.Locale,
+			},
+		}, nil
+	}
+}
+
+type SetFallbacksInput struct {
+	ProjectId string
+	Locale    string
+	// in order of preference, empty to go back to the default chain
+	Fallbacks []string
+}
+
+func SetFallbacks(eventStore EventStore) func(ctx context.Context, input SetFallbacksInput) ([]Event, error) {
+	return func(ctx context.Context, input SetFallbacksInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		if !Contains(project.Locales, input.Locale) {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		fallbacks, err := CanonicalizeLocales(input.Fallbacks)
+		if err != nil {
+			validation.Add("Fallbacks", err.Error())
+		}
+		for _, fallback := range fallbacks {
+			if fallback == input.Locale {
+				validation.Add("Fallbacks", fmt.Sprintf("%s can't fall back to itself", fallback))
+			} else if !Contains(project.Locales, fallback) {
+				validation.Add("Fallbacks", fmt.Sprintf("%s is not one of the project's locales", fallback))
+			}
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			FallbacksChanged{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Locale:    input.Locale,
+				Fallbacks: fallbacks,
+			},
 		}, nil
 	}
 }
@@ -91,18 +330,347 @@ func UpdateProject(eventStore EventStore) func(ctx context.Context, input Update
 type CreateKeyInput struct {
 	ProjectId string
 	Id        string
+	// initial translation values by locale, empty values are skipped, for plural keys they're the other category
+	Values map[string]string
+	Plural bool
+	KeyMetadata
+}
+
+func CreateKey(eventStore EventStore) func(ctx context.Context, input CreateKeyInput) ([]Event, error) {
+	return func(ctx context.Context, input CreateKeyInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		validateKeyId(&validation, "Id", project, input.Id)
+		metadata := validateKeyMetadata(&validation, input.KeyMetadata)
+		for locale, value := range input.Values {
+			if value != "" && !Contains(project.Locales, locale) {
+				validation.Add("Values", fmt.Sprintf("%s is not one of the project's locales", locale))
+			}
+			if isTooLong(value, metadata.MaxLength) {
+				validation.Add("Values", fmt.Sprintf("%s is longer than %d characters", locale, metadata.MaxLength))
+			} else if problem := messageFormatProblem(value); problem != "" {
+				validation.Add("Values", fmt.Sprintf("%s %s", locale, problem))
+			}
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		events := []Event{
+			KeyCreated{
+				EventBase:   NewEventBase(ctx, input.ProjectId),
+				ProjectId:   input.ProjectId,
+				Id:          input.Id,
+				Plural:      input.Plural,
+				KeyMetadata: metadata,
+			},
+		}
+
+		locales := make([]string, 0, len(input.Values))
+		for locale := range input.Values {
+			locales = append(locales, locale)
+		}
+		sort.Strings(locales)
+		for _, locale := range locales {
+			if input.Values[locale] == "" {
+				continue
+			}
+			if input.Plural {
+				events = append(events, PluralTranslationUpdated{
+					EventBase: NewEventBase(ctx, input.ProjectId),
+					ProjectId: input.ProjectId,
+					KeyId:     input.Id,
+					Id:        locale,
+					Plurals:   map[PluralCategory]string{PluralOther: input.Values[locale]},
+				})
+				continue
+			}
+			events = append(events, TranslationUpdated{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				KeyId:     input.Id,
+				Id:        locale,
+				Value:     input.Values[locale],
+			})
+		}
+		return events, nil
+	}
+}
+
+// validateKeyId checks id can be used for a new key in project
+func validateKeyId(validation *ValidationError, field string, project *Project, id string) {
+	switch {
+	case id == "":
+		validation.Add(field, "id is required")
+	case len(id) > maxKeyIdLength:
+		validation.Add(field, fmt.Sprintf("id can be at most %d characters", maxKeyIdLength))
+	case strings.IndexFunc(id, unicode.IsSpace) >= 0:
+		validation.Add(field, "id can't contain whitespace")
+	case id == "." || id == "..":
+		// urls with them are cleaned up before they're routed, escaped or not, so the key's pages couldn't be reached
+		validation.Add(field, "id can't be . or ..")
+	case project.KeysById[id] != nil:
+		validation.Add(field, fmt.Sprintf("%s already exists", id))
+	}
+}
+
+// validateKeyMetadata checks metadata and returns it cleaned up, tags are trimmed and deduplicated
+func validateKeyMetadata(validation *ValidationError, metadata KeyMetadata) KeyMetadata {
+	if metadata.MaxLength < 0 {
+		validation.Add("MaxLength", "max length can't be negative")
+	}
+
+	tags := []string{}
+	for _, tag := range metadata.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return KeyMetadata{
+		Description: strings.TrimSpace(metadata.Description),
+		Notes:       strings.TrimSpace(metadata.Notes),
+		MaxLength:   metadata.MaxLength,
+		Tags:        tags,
+	}
+}
+
+// messageFormatProblem explains why value isn't valid ICU MessageFormat, empty when it is
+func messageFormatProblem(value string) string {
+	parseErr := CheckMessageFormat(value)
+	if parseErr == nil {
+		return ""
+	}
+	return fmt.Sprintf("isn't valid ICU MessageFormat at line %d, column %d: %s", parseErr.Line, parseErr.Column, parseErr.Message)
+}
+
+// isTooLong reports whether value has more than maxLength characters, a maxLength of 0 is no limit
+func isTooLong(value string, maxLength int) bool {
+	return maxLength > 0 && utf8.RuneCountInString(value) > maxLength
+}
+
+type UpdateKeyMetadataInput struct {
+	ProjectId string
+	Id        string
+	KeyMetadata
+}
+
+func UpdateKeyMetadata(eventStore EventStore) func(ctx context.Context, input UpdateKeyMetadataInput) ([]Event, error) {
+	return func(ctx context.Context, input UpdateKeyMetadataInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := project.KeysById[input.Id]
+		if !ok {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		metadata := validateKeyMetadata(&validation, input.KeyMetadata)
+		for _, locale := range project.Locales {
+			translation, ok := key.TranslationsById[locale]
+			if !ok {
+				continue
+			}
+			tooLong := isTooLong(translation.Value, metadata.MaxLength)
+			for _, value := range translation.Plurals {
+				tooLong = tooLong || isTooLong(value, metadata.MaxLength)
+			}
+			if tooLong {
+				validation.Add("MaxLength", fmt.Sprintf("the %s translation is already longer than %d characters", locale, metadata.MaxLength))
+			}
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			KeyMetadataUpdated{
+				EventBase:   NewEventBase(ctx, input.ProjectId),
+				ProjectId:   input.ProjectId,
+				Id:          input.Id,
+				KeyMetadata: metadata,
+			},
+		}, nil
+	}
+}
+
+type SetKeyPluralInput struct {
+	ProjectId string
+	Id        string
+	Plural    bool
+}
+
+// SetKeyPlural turns a key into a plural key or back, values carry over as the other category
+func SetKeyPlural(eventStore EventStore) func(ctx context.Context, input SetKeyPluralInput) ([]Event, error) {
+	return func(ctx context.Context, input SetKeyPluralInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := project.KeysById[input.Id]
+		if !ok {
+			return nil, ErrorNotFound
+		}
+		if key.Plural == input.Plural {
+			return nil, nil
+		}
+
+		return []Event{
+			KeyPluralChanged{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Id:        input.Id,
+				Plural:    input.Plural,
+			},
+		}, nil
+	}
+}
+
+type DeleteKeyInput struct {
+	ProjectId string
+	Id        string
+}
+
+func DeleteKey(eventStore EventStore) func(ctx context.Context, input DeleteKeyInput) ([]Event, error) {
+	return func(ctx context.Context, input DeleteKeyInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := project.KeysById[input.Id]; !ok {
+			return nil, ErrorNotFound
+		}
+
+		return []Event{
+			KeyDeleted{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Id:        input.Id,
+			},
+		}, nil
+	}
+}
+
+type RenameKeyInput struct {
+	ProjectId string
+	Id        string
+	NewId     string
 }
 
-func CreateKey() func(ctx context.Context, input CreateKeyInput) (Event, error) {
-	return func(ctx context.Context, input CreateKeyInput) (Event, error) {
-		return KeyCreated{
-			EventBase: NewEventBase(ctx, input.ProjectId),
-			ProjectId: input.ProjectId,
-			Id:        input.Id,
+func RenameKey(eventStore EventStore) func(ctx context.Context, input RenameKeyInput) ([]Event, error) {
+	return func(ctx context.Context, input RenameKeyInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := project.KeysById[input.Id]; !ok {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		validateKeyId(&validation, "NewId", project, input.NewId)
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			KeyRenamed{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Id:        input.Id,
+				NewId:     input.NewId,
+			},
 		}, nil
 	}
 }
 
+type MoveKeyInput struct {
+	ProjectId   string
+	Id          string
+	ToProjectId string
+}
+
+// MoveKey moves a key and all of its translations to another project, which needs every locale the key has a value in
+func MoveKey(eventStore EventStore) func(ctx context.Context, input MoveKeyInput) ([]Event, error) {
+	return func(ctx context.Context, input MoveKeyInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := project.KeysById[input.Id]
+		if !ok {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		toProject, err := GetProject(ctx, eventStore, input.ToProjectId)
+		if err == ErrorNotFound {
+			validation.Add("ToProjectId", "project doesn't exist")
+			return nil, validation.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if toProject.Id == project.Id {
+			validation.Add("ToProjectId", "key is already in this project")
+		} else if _, ok := toProject.KeysById[input.Id]; ok {
+			validation.Add("ToProjectId", fmt.Sprintf("%s already has a key %s", toProject.Name, input.Id))
+		}
+		values := map[string]string{}
+		statuses := map[string]TranslationStatus{}
+		var plurals map[string]map[PluralCategory]string
+		if key.Plural {
+			plurals = map[string]map[PluralCategory]string{}
+		}
+		missing := []string{}
+		for locale, translation := range key.TranslationsById {
+			if !translation.hasValue() {
+				continue
+			}
+			values[locale] = translation.Value
+			statuses[locale] = translation.Status
+			if key.Plural {
+				plurals[locale] = maps.Clone(translation.Plurals)
+			}
+			if !Contains(toProject.Locales, locale) {
+				missing = append(missing, locale)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			validation.Add("ToProjectId", fmt.Sprintf("%s is missing locales %s", toProject.Name, strings.Join(missing, ", ")))
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		// the same event goes to both projects, so each one's stream is complete on its own
+		events := []Event{}
+		for _, aggregateId := range []string{project.Id, toProject.Id} {
+			events = append(events, KeyMoved{
+				EventBase:     NewEventBase(ctx, aggregateId),
+				Id:            input.Id,
+				FromProjectId: project.Id,
+				ToProjectId:   toProject.Id,
+				DateCreated:   key.DateCreated,
+				Values:        values,
+				Statuses:      statuses,
+				Plural:        key.Plural,
+				Plurals:       plurals,
+				KeyMetadata:   key.KeyMetadata,
+			})
+		}
+		return events, nil
+	}
+}
+
 type UpdateTranslationInput struct {
 	ProjectId string
 	KeyId     string
@@ -110,32 +678,812 @@ type UpdateTranslationInput struct {
 	Value     string
 }
 
-func UpdateTranslation() func(ctx context.Context, input UpdateTranslationInput) (Event, error) {
-	return func(ctx context.Context, input UpdateTranslationInput) (Event, error) {
-		return TranslationUpdated{
-			EventBase: NewEventBase(ctx, input.ProjectId),
-			ProjectId: input.ProjectId,
-			KeyId:     input.KeyId,
-			Id:        input.Id,
-			Value:     input.Value,
+func UpdateTranslation(eventStore EventStore) func(ctx context.Context, input UpdateTranslationInput) ([]Event, error) {
+	return func(ctx context.Context, input UpdateTranslationInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		key, ok := project.KeysById[input.KeyId]
+		if !ok {
+			validation.Add("KeyId", fmt.Sprintf("%s doesn't exist", input.KeyId))
+		} else if key.Plural {
+			validation.Add("Value", "key is plural, it needs a value per plural category")
+		} else if isTooLong(input.Value, key.MaxLength) {
+			validation.Add("Value", fmt.Sprintf("can be at most %d characters, this is %d", key.MaxLength, utf8.RuneCountInString(input.Value)))
+		} else if problem := messageFormatProblem(input.Value); problem != "" {
+			validation.Add("Value", problem)
+		}
+		if !Contains(project.Locales, input.Id) {
+			validation.Add("Id", fmt.Sprintf("%s is not one of the project's locales", input.Id))
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+		if key.TranslationsById[input.Id].Value == input.Value {
+			return nil, nil
+		}
+
+		return []Event{
+			TranslationUpdated{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				KeyId:     input.KeyId,
+				Id:        input.Id,
+				Value:     input.Value,
+			},
 		}, nil
 	}
 }
 
-var ErrorNotFound = errors.New("not found")
+type UpdatePluralTranslationInput struct {
+	ProjectId string
+	KeyId     string
+	Id        string
+	// by category, only the categories of the locale are allowed, see PluralCategories
+	Plurals map[PluralCategory]string
+}
 
-func GetProject(ctx context.Context, eventStore EventStore, id string) (*Project, error) {
-	var project Project
-	err := ReduceWith(ctx, &project, eventStore.NewGenerator(AggregateIds(id)))
-	if project.Id == "" {
-		return nil, ErrorNotFound
-	}
-	return &project, err
+// UpdatePluralTranslation replaces the plural variants of a translation of a plural key
+func UpdatePluralTranslation(eventStore EventStore) func(ctx context.Context, input UpdatePluralTranslationInput) ([]Event, error) {
+	return func(ctx context.Context, input UpdatePluralTranslationInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		key, ok := project.KeysById[input.KeyId]
+		if !ok {
+			validation.Add("KeyId", fmt.Sprintf("%s doesn't exist", input.KeyId))
+		} else if !key.Plural {
+			validation.Add("KeyId", fmt.Sprintf("%s isn't plural", input.KeyId))
+		}
+		if !Contains(project.Locales, input.Id) {
+			validation.Add("Id", fmt.Sprintf("%s is not one of the project's locales", input.Id))
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		plurals := map[PluralCategory]string{}
+		categories := PluralCategories(input.Id)
+		for _, category := range PluralCategoriesInOrder {
+			value, ok := input.Plurals[category]
+			if !ok {
+				continue
+			}
+			if !Contains(categories, category) {
+				validation.Add("Plurals", fmt.Sprintf("%s doesn't use the %s category", input.Id, category))
+			} else if isTooLong(value, key.MaxLength) {
+				validation.Add("Plurals", fmt.Sprintf("%s can be at most %d characters, this is %d", category, key.MaxLength, utf8.RuneCountInString(value)))
+			} else if problem := messageFormatProblem(value); problem != "" {
+				validation.Add("Plurals", fmt.Sprintf("%s %s", category, problem))
+			}
+			if value != "" {
+				plurals[category] = value
+			}
+		}
+		for category := range input.Plurals {
+			if !Contains(PluralCategoriesInOrder, category) {
+				validation.Add("Plurals", fmt.Sprintf("%s is not a plural category", category))
+			}
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+		if maps.Equal(key.TranslationsById[input.Id].Plurals, plurals) {
+			return nil, nil
+		}
 
+		return []Event{
+			PluralTranslationUpdated{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				KeyId:     input.KeyId,
+				Id:        input.Id,
+				Plurals:   plurals,
+			},
+		}, nil
+	}
 }
 
-func GetProjectList(ctx context.Context, eventStore EventStore) (*ProjectList, error) {
-	var projectList ProjectList
-	err := ReduceWith(ctx, &projectList, eventStore.NewGenerator())
+type DeleteTranslationInput struct {
+	ProjectId string
+	KeyId     string
+	Id        string
+}
+
+func DeleteTranslation(eventStore EventStore) func(ctx context.Context, input DeleteTranslationInput) ([]Event, error) {
+	return func(ctx context.Context, input DeleteTranslationInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := project.KeysById[input.KeyId]
+		if !ok {
+			return nil, ErrorNotFound
+		}
+		translation, ok := key.TranslationsById[input.Id]
+		if !ok {
+			return nil, ErrorNotFound
+		}
+		// translations are only ever cleared, there's nothing to do for one that's already empty
+		if !translation.hasValue() {
+			return nil, nil
+		}
+
+		return []Event{
+			TranslationDeleted{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				KeyId:     input.KeyId,
+				Id:        input.Id,
+			},
+		}, nil
+	}
+}
+
+type SubmitTranslationInput struct {
+	ProjectId string
+	KeyId     string
+	Id        string
+}
+
+func SubmitTranslation(eventStore EventStore) func(ctx context.Context, input SubmitTranslationInput) ([]Event, error) {
+	return func(ctx context.Context, input SubmitTranslationInput) ([]Event, error) {
+		err := checkReview(ctx, eventStore, input.ProjectId, input.KeyId, input.Id, StatusNeedsReview)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			TranslationSubmitted{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				KeyId:     input.KeyId,
+				Id:        input.Id,
+			},
+		}, nil
+	}
+}
+
+type ApproveTranslationInput struct {
+	ProjectId string
+	KeyId     string
+	Id        string
+}
+
+func ApproveTranslation(eventStore EventStore) func(ctx context.Context, input ApproveTranslationInput) ([]Event, error) {
+	return func(ctx context.Context, input ApproveTranslationInput) ([]Event, error) {
+		err := checkReview(ctx, eventStore, input.ProjectId, input.KeyId, input.Id, StatusApproved)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			TranslationApproved{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				KeyId:     input.KeyId,
+				Id:        input.Id,
+			},
+		}, nil
+	}
+}
+
+type RejectTranslationInput struct {
+	ProjectId string
+	KeyId     string
+	Id        string
+	Reason    string
+}
+
+func RejectTranslation(eventStore EventStore) func(ctx context.Context, input RejectTranslationInput) ([]Event, error) {
+	return func(ctx context.Context, input RejectTranslationInput) ([]Event, error) {
+		err := checkReview(ctx, eventStore, input.ProjectId, input.KeyId, input.Id, StatusRejected)
+		if err != nil {
+			return nil, err
+		}
+		var validation ValidationError
+		if strings.TrimSpace(input.Reason) == "" {
+			validation.Add("Reason", "say why it's rejected")
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			TranslationRejected{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				KeyId:     input.KeyId,
+				Id:        input.Id,
+				Reason:    strings.TrimSpace(input.Reason),
+			},
+		}, nil
+	}
+}
+
+// checkReview checks the translation of keyId in locale exists and can be moved to status
+func checkReview(ctx context.Context, eventStore EventStore, projectId string, keyId string, locale string, status TranslationStatus) error {
+	project, err := GetProject(ctx, eventStore, projectId)
+	if err != nil {
+		return err
+	}
+	translation := project.translation(keyId, locale)
+	if translation == nil {
+		return ErrorNotFound
+	}
+
+	var validation ValidationError
+	validateReview(&validation, translation, status)
+	return validation.Err()
+}
+
+type AddCommentInput struct {
+	ProjectId string
+	KeyId     string
+	Locale    string
+	Text      string
+}
+
+// AddComment adds to the open thread about the project, key or locale, or starts one
+func AddComment(eventStore EventStore, threads *CommentThreads) func(ctx context.Context, input AddCommentInput) ([]Event, error) {
+	return func(ctx context.Context, input AddCommentInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		if _, ok := project.KeysById[input.KeyId]; input.KeyId != "" && !ok {
+			validation.Add("KeyId", fmt.Sprintf("%s doesn't exist", input.KeyId))
+		}
+		if input.Locale != "" && !Contains(project.Locales, input.Locale) {
+			validation.Add("Locale", fmt.Sprintf("%s is not one of the project's locales", input.Locale))
+		}
+		if input.Locale != "" && input.KeyId == "" {
+			validation.Add("Locale", "threads are about a translation, a key or the project, not a locale")
+		}
+		if strings.TrimSpace(input.Text) == "" {
+			validation.Add("Text", "comment can't be empty")
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		thread, err := threads.OpenThread(ctx, input.ProjectId, input.KeyId, input.Locale)
+		if err != nil {
+			return nil, err
+		}
+		threadId := uuid.NewString()
+		if thread != nil {
+			threadId = thread.Id
+		}
+
+		return []Event{
+			CommentAdded{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				Id:        uuid.NewString(),
+				ThreadId:  threadId,
+				ProjectId: input.ProjectId,
+				KeyId:     input.KeyId,
+				Locale:    input.Locale,
+				Text:      strings.TrimSpace(input.Text),
+			},
+		}, nil
+	}
+}
+
+type EditCommentInput struct {
+	ProjectId string
+	ThreadId  string
+	Id        string
+	Text      string
+}
+
+// EditComment changes the text of a comment, only its author can
+func EditComment(threads *CommentThreads) func(ctx context.Context, input EditCommentInput) ([]Event, error) {
+	return func(ctx context.Context, input EditCommentInput) ([]Event, error) {
+		thread, err := threads.Thread(ctx, input.ThreadId)
+		if err != nil {
+			return nil, err
+		}
+		if thread == nil || thread.ProjectId != input.ProjectId {
+			return nil, ErrorNotFound
+		}
+		i := slices.IndexFunc(thread.Comments, func(comment Comment) bool { return comment.Id == input.Id })
+		if i < 0 {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		if thread.Comments[i].AuthorId != GetActor(ctx) {
+			validation.Add("Text", "only the author of a comment can edit it")
+		}
+		if strings.TrimSpace(input.Text) == "" {
+			validation.Add("Text", "comment can't be empty")
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			CommentEdited{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				Id:        input.Id,
+				ThreadId:  input.ThreadId,
+				ProjectId: input.ProjectId,
+				Text:      strings.TrimSpace(input.Text),
+			},
+		}, nil
+	}
+}
+
+type ResolveCommentThreadInput struct {
+	ProjectId string
+	Id        string
+}
+
+func ResolveCommentThread(threads *CommentThreads) func(ctx context.Context, input ResolveCommentThreadInput) ([]Event, error) {
+	return func(ctx context.Context, input ResolveCommentThreadInput) ([]Event, error) {
+		thread, err := threads.Thread(ctx, input.Id)
+		if err != nil {
+			return nil, err
+		}
+		if thread == nil || thread.ProjectId != input.ProjectId {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		if thread.Resolved {
+			validation.Add("Id", "thread is already resolved")
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			CommentResolved{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ThreadId:  input.Id,
+				ProjectId: input.ProjectId,
+			},
+		}, nil
+	}
+}
+
+type SetQARuleEnabledInput struct {
+	ProjectId string
+	Rule      string
+	Enabled   bool
+}
+
+// SetQARuleEnabled turns a QA rule on or off for a project, rules has to have it
+func SetQARuleEnabled(eventStore EventStore, rules *QARules) func(ctx context.Context, input SetQARuleEnabledInput) ([]Event, error) {
+	return func(ctx context.Context, input SetQARuleEnabledInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		if _, err := rules.Get(input.Rule); err != nil {
+			validation.Add("Rule", fmt.Sprintf("%s isn't a QA rule", input.Rule))
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+		if project.QARuleEnabled(input.Rule) == input.Enabled {
+			return nil, nil
+		}
+
+		if input.Enabled {
+			return []Event{
+				QARuleEnabled{
+					EventBase: NewEventBase(ctx, input.ProjectId),
+					ProjectId: input.ProjectId,
+					Rule:      input.Rule,
+				},
+			}, nil
+		}
+		return []Event{
+			QARuleDisabled{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				ProjectId: input.ProjectId,
+				Rule:      input.Rule,
+			},
+		}, nil
+	}
+}
+
+type AddGlossaryTermInput struct {
+	ProjectId string
+	GlossaryEntry
+}
+
+func AddGlossaryTerm(eventStore EventStore) func(ctx context.Context, input AddGlossaryTermInput) ([]Event, error) {
+	return func(ctx context.Context, input AddGlossaryTermInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		entry := validateGlossaryEntry(&validation, project, "", input.GlossaryEntry)
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			GlossaryTermAdded{
+				EventBase:     NewEventBase(ctx, input.ProjectId),
+				Id:            uuid.NewString(),
+				ProjectId:     input.ProjectId,
+				GlossaryEntry: entry,
+			},
+		}, nil
+	}
+}
+
+type UpdateGlossaryTermInput struct {
+	ProjectId string
+	Id        string
+	GlossaryEntry
+}
+
+func UpdateGlossaryTerm(eventStore EventStore) func(ctx context.Context, input UpdateGlossaryTermInput) ([]Event, error) {
+	return func(ctx context.Context, input UpdateGlossaryTermInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		term, ok := project.GlossaryById[input.Id]
+		if !ok {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		entry := validateGlossaryEntry(&validation, project, input.Id, input.GlossaryEntry)
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+		if entry.Term == term.Term && entry.Description == term.Description && entry.DoNotTranslate == term.DoNotTranslate &&
+			maps.Equal(entry.Translations, term.Translations) {
+			return nil, nil
+		}
+
+		return []Event{
+			GlossaryTermUpdated{
+				EventBase:     NewEventBase(ctx, input.ProjectId),
+				Id:            input.Id,
+				ProjectId:     input.ProjectId,
+				GlossaryEntry: entry,
+			},
+		}, nil
+	}
+}
+
+// validateGlossaryEntry checks entry can be the glossary term id of project, "" for a new one, and returns it cleaned up,
+// empty translations are dropped
+func validateGlossaryEntry(validation *ValidationError, project *Project, id string, entry GlossaryEntry) GlossaryEntry {
+	term := strings.TrimSpace(entry.Term)
+	if term == "" {
+		validation.Add("Term", "term is required")
+	}
+	for _, other := range project.GlossaryById {
+		if other.Id != id && strings.EqualFold(other.Term, term) {
+			validation.Add("Term", fmt.Sprintf("%s is already in the glossary", other.Term))
+		}
+	}
+
+	translations := map[string]string{}
+	for locale, value := range entry.Translations {
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+		case !Contains(project.Locales, locale):
+			validation.Add("Translations", fmt.Sprintf("%s isn't one of the project's locales", locale))
+		case locale == project.SourceLocale:
+			validation.Add("Translations", fmt.Sprintf("%s is the source locale, the term is already in it", locale))
+		default:
+			translations[locale] = value
+		}
+	}
+	if entry.DoNotTranslate && len(translations) > 0 {
+		validation.Add("Translations", "a term that isn't translated can't have translations")
+	}
+	if len(translations) == 0 {
+		translations = nil
+	}
+
+	return GlossaryEntry{
+		Term:           term,
+		Description:    strings.TrimSpace(entry.Description),
+		DoNotTranslate: entry.DoNotTranslate,
+		Translations:   translations,
+	}
+}
+
+type RemoveGlossaryTermInput struct {
+	ProjectId string
+	Id        string
+}
+
+func RemoveGlossaryTerm(eventStore EventStore) func(ctx context.Context, input RemoveGlossaryTermInput) ([]Event, error) {
+	return func(ctx context.Context, input RemoveGlossaryTermInput) ([]Event, error) {
+		project, err := GetProject(ctx, eventStore, input.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := project.GlossaryById[input.Id]; !ok {
+			return nil, ErrorNotFound
+		}
+
+		return []Event{
+			GlossaryTermRemoved{
+				EventBase: NewEventBase(ctx, input.ProjectId),
+				Id:        input.Id,
+				ProjectId: input.ProjectId,
+			},
+		}, nil
+	}
+}
+
+var ErrorNotFound = errors.New("not found")
+
+const maxKeyIdLength = 255
+
+func GetProject(ctx context.Context, eventStore EventStore, id string) (*Project, error) {
+	project, _, err := readProject(ctx, eventStore, id)
+	if project.Id == "" || project.Deleted {
+		return nil, ErrorNotFound
+	}
+	if err != nil {
+		return &project, err
+	}
+	recordReadVersion(ctx, id, project.Version)
+	return &project, nil
+}
+
+// GetProjectHistory returns the events of a project, newest first, it isn't part of Project so snapshots don't grow with it
+func GetProjectHistory(ctx context.Context, eventStore EventStore, id string) ([]Event, error) {
+	var events eventList
+	err := ReduceWith(ctx, &events, eventStore.NewGenerator(AggregateIds(id)))
+	if err != nil {
+		return nil, err
+	}
+	history := make([]Event, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		history = append(history, events[i])
+	}
+	return history, nil
+}
+
+// GetKeyHistory returns the events of a key, newest first, following it back across renames and moves
+func GetKeyHistory(ctx context.Context, eventStore EventStore, projectId string, keyId string) ([]Event, error) {
+	history := []Event{}
+	before := int64(0)
+	for projectId != "" {
+		var events eventList
+		err := ReduceWith(ctx, &events, eventStore.NewGenerator(AggregateIds(projectId)))
+		if err != nil {
+			return nil, err
+		}
+
+		fromProjectId := ""
+		for i := len(events) - 1; i >= 0 && fromProjectId == ""; i-- {
+			if before > 0 && events[i].GetPosition() >= before {
+				continue
+			}
+			switch e := events[i].(type) {
+			case KeyCreated:
+				if e.Id == keyId {
+					return append(history, e), nil
+				}
+			case KeyRenamed:
+				if e.NewId == keyId {
+					history = append(history, e)
+					keyId = e.Id
+				}
+			case KeyMoved:
+				// the copy written to the project the key left is the same move, only the arriving one counts
+				if e.Id == keyId && e.ToProjectId == projectId {
+					history = append(history, e)
+					fromProjectId = e.FromProjectId
+					before = e.GetPosition()
+				}
+			case TranslationUpdated:
+				if e.KeyId == keyId {
+					history = append(history, e)
+				}
+			case TranslationDeleted:
+				if e.KeyId == keyId {
+					history = append(history, e)
+				}
+			case PluralTranslationUpdated:
+				if e.KeyId == keyId {
+					history = append(history, e)
+				}
+			case TranslationSubmitted:
+				if e.KeyId == keyId {
+					history = append(history, e)
+				}
+			case TranslationApproved:
+				if e.KeyId == keyId {
+					history = append(history, e)
+				}
+			case TranslationRejected:
+				if e.KeyId == keyId {
+					history = append(history, e)
+				}
+			case KeyPluralChanged:
+				if e.Id == keyId {
+					history = append(history, e)
+				}
+			case KeyMetadataUpdated:
+				if e.Id == keyId {
+					history = append(history, e)
+				}
+			}
+		}
+		projectId = fromProjectId
+	}
+	return history, nil
+}
+
+func GetProjectList(ctx context.Context, eventStore EventStore) (*ProjectList, error) {
+	var projectList ProjectList
+	err := ReduceWith(ctx, &projectList, eventStore.NewGenerator(EventTypes(projectListEventTypes...)))
 	return &projectList, err
 }
+
+var ErrorInvalidCredentials = errors.New("invalid username or password")
+
+const minPasswordLength = 8
+
+type RegisterUserInput struct {
+	Username string
+	Password string
+}
+
+func RegisterUser(eventStore EventStore) func(ctx context.Context, input RegisterUserInput) ([]Event, error) {
+	return func(ctx context.Context, input RegisterUserInput) ([]Event, error) {
+		users, err := GetUsers(ctx, eventStore)
+		if err != nil {
+			return nil, err
+		}
+
+		var validation ValidationError
+		username := strings.TrimSpace(input.Username)
+		if username == "" {
+			validation.Add("Username", "username is required")
+		} else if _, ok := users.UsersByUsername[username]; ok {
+			validation.Add("Username", "username is already taken")
+		}
+		if len(input.Password) < minPasswordLength {
+			validation.Add("Password", fmt.Sprintf("password must be at least %d characters", minPasswordLength))
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		passwordHash, err := HashPassword(input.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		id := uuid.NewString()
+		return []Event{
+			UserRegistered{
+				EventBase:    NewEventBase(ctx, usersAggregateId),
+				Id:           id,
+				Username:     username,
+				PasswordHash: passwordHash,
+			},
+		}, nil
+	}
+}
+
+type IssueApiTokenInput struct {
+	UserId string
+	Name   string
+	// from NewApiToken, only its hash is stored
+	Token string
+}
+
+func IssueApiToken(eventStore EventStore) func(ctx context.Context, input IssueApiTokenInput) ([]Event, error) {
+	return func(ctx context.Context, input IssueApiTokenInput) ([]Event, error) {
+		users, err := GetUsers(ctx, eventStore)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := users.UsersById[input.UserId]; !ok {
+			return nil, ErrorNotFound
+		}
+
+		var validation ValidationError
+		if strings.TrimSpace(input.Name) == "" {
+			validation.Add("Name", "name is required")
+		}
+		if err := validation.Err(); err != nil {
+			return nil, err
+		}
+
+		return []Event{
+			ApiTokenIssued{
+				EventBase: NewEventBase(ctx, input.UserId),
+				Id:        uuid.NewString(),
+				UserId:    input.UserId,
+				Name:      strings.TrimSpace(input.Name),
+				TokenHash: HashApiToken(input.Token),
+			},
+		}, nil
+	}
+}
+
+type RevokeApiTokenInput struct {
+	UserId string
+	Id     string
+}
+
+func RevokeApiToken(eventStore EventStore) func(ctx context.Context, input RevokeApiTokenInput) ([]Event, error) {
+	return func(ctx context.Context, input RevokeApiTokenInput) ([]Event, error) {
+		users, err := GetUsers(ctx, eventStore)
+		if err != nil {
+			return nil, err
+		}
+		user, ok := users.UsersById[input.UserId]
+		if !ok {
+			return nil, ErrorNotFound
+		}
+		if _, ok := user.ApiTokensById[input.Id]; !ok {
+			return nil, ErrorNotFound
+		}
+
+		return []Event{
+			ApiTokenRevoked{
+				EventBase: NewEventBase(ctx, input.UserId),
+				Id:        input.Id,
+				UserId:    input.UserId,
+			},
+		}, nil
+	}
+}
+
+func GetUsers(ctx context.Context, eventStore EventStore) (*Users, error) {
+	var users Users
+	err := ReduceWith(ctx, &users, eventStore.NewGenerator(EventTypes(usersEventTypes...)))
+	if err != nil {
+		return nil, err
+	}
+	recordReadVersion(ctx, usersAggregateId, users.Version)
+	return &users, nil
+}
+
+func Authenticate(ctx context.Context, eventStore EventStore, username string, password string) (*User, error) {
+	users, err := GetUsers(ctx, eventStore)
+	if err != nil {
+		return nil, err
+	}
+	user, ok := users.UsersByUsername[strings.TrimSpace(username)]
+	if !ok || !CheckPassword(user.PasswordHash, password) {
+		return nil, ErrorInvalidCredentials
+	}
+	return user, nil
+}
+
+func AuthenticateApiToken(ctx context.Context, eventStore EventStore, token string) (*User, error) {
+	users, err := GetUsers(ctx, eventStore)
+	if err != nil {
+		return nil, err
+	}
+	user, ok := users.UsersByApiToken[HashApiToken(token)]
+	if !ok {
+		return nil, ErrorInvalidCredentials
+	}
+	return user, nil
+}
@@ -0,0 +1,443 @@
+package translations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestCommandValidation(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+
+	for _, test := range []struct {
+		name   string
+		events func() ([]Event, error)
+		field  string
+	}{
+		{"duplicate key", func() ([]Event, error) {
+			return CreateKey(eventStore)(ctx, CreateKeyInput{ProjectId: "p1", Id: "header_1"})
+		}, "Id"},
+		{"key id with whitespace", func() ([]Event, error) {
+			return CreateKey(eventStore)(ctx, CreateKeyInput{ProjectId: "p1", Id: "header 2"})
+		}, "Id"},
+		{"key id that's a path segment of its own", func() ([]Event, error) {
+			return CreateKey(eventStore)(ctx, CreateKeyInput{ProjectId: "p1", Id: ".."})
+		}, "Id"},
+		{"value for unknown locale", func() ([]Event, error) {
+			return CreateKey(eventStore)(ctx, CreateKeyInput{ProjectId: "p1", Id: "header_2", Values: map[string]string{"fr": "Bonjour"}})
+		}, "Values"},
+		{"missing key", func() ([]Event, error) {
+			return UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_2", Id: "en"})
+		}, "KeyId"},
+		{"unknown locale", func() ([]Event, error) {
+			return UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "fr"})
+		}, "Id"},
+		{"project without a name", func() ([]Event, error) {
+			return CreateProject()(ctx, CreateProjectInput{Name: " "})
+		}, "Name"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.events()
+			var validation ValidationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if validation.Fields[test.field] == "" {
+				t.Errorf("expected an error for %s, got %v", test.field, validation.Fields)
+			}
+		})
+	}
+
+	_, err := CreateKey(eventStore)(ctx, CreateKeyInput{ProjectId: "missing", Id: "header_1"})
+	if !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected not found for a missing project, got %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, append(greetingProject(), fixtureProject("p2", []string{"en"}, nil)...)...)
+
+	eventStore.mustApply(DeleteTranslation(eventStore)(ctx, DeleteTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"}))
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if translation := project.KeysById["header_1"].TranslationsById["en"]; translation.Value != "" || translation.Status != StatusUntranslated {
+		t.Errorf("expected the en translation to be cleared, got %+v", translation)
+	}
+	events, err := DeleteTranslation(eventStore)(ctx, DeleteTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"})
+	if err != nil || len(events) != 0 {
+		t.Errorf("expected clearing an empty translation to do nothing, got %v %v", events, err)
+	}
+	_, err = DeleteTranslation(eventStore)(ctx, DeleteTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "fr"})
+	if !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected an unknown locale to not be found, got %v", err)
+	}
+
+	eventStore.mustApply(DeleteKey(eventStore)(ctx, DeleteKeyInput{ProjectId: "p1", Id: "header_1"}))
+	project, err = GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := project.KeysById["header_1"]; ok {
+		t.Errorf("expected header_1 to be deleted")
+	}
+	_, err = DeleteKey(eventStore)(ctx, DeleteKeyInput{ProjectId: "p1", Id: "header_1"})
+	if !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected a deleted key to not be found, got %v", err)
+	}
+
+	eventStore.mustApply(DeleteProject(eventStore)(ctx, DeleteProjectInput{Id: "p1"}))
+	_, err = GetProject(ctx, eventStore, "p1")
+	if !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected a deleted project to not be found, got %v", err)
+	}
+	projectList, err := GetProjectList(ctx, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := projectList.ProjectsById["p1"]; ok || projectList.ProjectsById["p2"] == nil {
+		t.Errorf("expected only p2 to be listed, got %v", projectList.ProjectsById)
+	}
+
+	_, err = DeleteProject(eventStore)(ctx, DeleteProjectInput{Id: "p1"})
+	if !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected a deleted project to not be deleted again, got %v", err)
+	}
+	_, err = CreateKey(eventStore)(ctx, CreateKeyInput{ProjectId: "p1", Id: "header_2"})
+	if !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected a deleted project to not be written to, got %v", err)
+	}
+}
+
+func TestRenameAndMoveKey(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, append(greetingProject(), fixtureProject("p2", []string{"en"}, nil)...)...)
+
+	eventStore.mustApply(RenameKey(eventStore)(ctx, RenameKeyInput{ProjectId: "p1", Id: "header_1", NewId: "home.header.title"}))
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.KeysById["header_1"] != nil || project.Resolve("home.header.title", "es").Value != "Hola" {
+		t.Errorf("expected the translations to follow the rename, got %+v", project.KeysById)
+	}
+
+	var validation ValidationError
+	err = eventStore.apply(MoveKey(eventStore)(ctx, MoveKeyInput{ProjectId: "p1", Id: "home.header.title", ToProjectId: "p2"}))
+	if !errors.As(err, &validation) || validation.Fields["ToProjectId"] == "" {
+		t.Fatalf("expected moving into a project without es to be invalid, got %v", err)
+	}
+	eventStore.mustApply(AddLocale(eventStore)(ctx, AddLocaleInput{ProjectId: "p2", Locale: "es"}))
+	eventStore.mustApply(MoveKey(eventStore)(ctx, MoveKeyInput{ProjectId: "p1", Id: "home.header.title", ToProjectId: "p2"}))
+
+	project, err = GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.KeysById) != 0 {
+		t.Errorf("expected the key to have left p1, got %+v", project.KeysById)
+	}
+	project, err = GetProject(ctx, eventStore, "p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Resolve("home.header.title", "en").Value != "Hello" || project.KeysById["home.header.title"].TranslationsById["es"].ProjectId != "p2" {
+		t.Errorf("expected the key to have arrived in p2, got %+v", project.KeysById)
+	}
+
+	history, err := GetKeyHistory(ctx, eventStore, "p2", "home.header.title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := []string{}
+	for _, event := range history {
+		types = append(types, TypeName(event))
+	}
+	expected := []string{"KeyMoved", "KeyRenamed", "TranslationUpdated", "TranslationUpdated", "KeyCreated"}
+	if !slices.Equal(types, expected) {
+		t.Errorf("expected history %v, got %v", expected, types)
+	}
+}
+
+func TestKeyHistory(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, append(greetingProject(), fixtureProject("p2", []string{"en"}, map[string]map[string]string{"header_1": {"en": "Welcome"}})...)...)
+
+	eventStore.mustApply(UpdateKeyMetadata(eventStore)(ctx, UpdateKeyMetadataInput{ProjectId: "p1", Id: "header_1", KeyMetadata: KeyMetadata{Description: "greeting on the home page"}}))
+	eventStore.mustApply(SubmitTranslation(eventStore)(ctx, SubmitTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"}))
+	eventStore.mustApply(RejectTranslation(eventStore)(ctx, RejectTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Reason: "too formal"}))
+	eventStore.mustApply(SubmitTranslation(eventStore)(ctx, SubmitTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"}))
+	eventStore.mustApply(ApproveTranslation(eventStore)(ctx, ApproveTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"}))
+	eventStore.mustApply(SetKeyPlural(eventStore)(ctx, SetKeyPluralInput{ProjectId: "p1", Id: "header_1", Plural: true}))
+	eventStore.mustApply(UpdatePluralTranslation(eventStore)(ctx, UpdatePluralTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Plurals: map[PluralCategory]string{"one": "Hello", "other": "Hello all"}}))
+
+	// p2's header_1 is another key with the same id, none of its events belong in p1's history
+	history, err := GetKeyHistory(ctx, eventStore, "p1", "header_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := []string{}
+	for _, event := range history {
+		types = append(types, TypeName(event))
+	}
+	expected := []string{
+		"PluralTranslationUpdated", "KeyPluralChanged",
+		"TranslationApproved", "TranslationSubmitted", "TranslationRejected", "TranslationSubmitted",
+		"KeyMetadataUpdated", "TranslationUpdated", "TranslationUpdated", "KeyCreated",
+	}
+	if !slices.Equal(types, expected) {
+		t.Errorf("expected history %v, got %v", expected, types)
+	}
+}
+
+func TestKeyMetadata(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+
+	var validation ValidationError
+	_, err := UpdateKeyMetadata(eventStore)(ctx, UpdateKeyMetadataInput{ProjectId: "p1", Id: "header_1", KeyMetadata: KeyMetadata{MaxLength: 3}})
+	if !errors.As(err, &validation) || validation.Fields["MaxLength"] == "" {
+		t.Fatalf("expected a max length shorter than the existing translations to be invalid, got %v", err)
+	}
+
+	eventStore.mustApply(UpdateKeyMetadata(eventStore)(ctx, UpdateKeyMetadataInput{ProjectId: "p1", Id: "header_1", KeyMetadata: KeyMetadata{
+		Description: " Main heading ",
+		MaxLength:   5,
+		Tags:        []string{"home", " home", ""},
+	}}))
+
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := project.KeysById["header_1"]
+	if key.Description != "Main heading" || !slices.Equal(key.Tags, []string{"home"}) {
+		t.Errorf("expected cleaned up metadata, got %+v", key.KeyMetadata)
+	}
+
+	_, err = UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "es", Value: "¡Hola!"})
+	if !errors.As(err, &validation) || validation.Fields["Value"] == "" {
+		t.Errorf("expected a value over the max length to be invalid, got %v", err)
+	}
+	_, err = UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "es", Value: "¡Hola"})
+	if err != nil {
+		t.Errorf("expected max length to count characters, not bytes, got %v", err)
+	}
+}
+
+func TestCommandPipelineConcurrencyConflict(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+	err := eventStore.Write(ctx, nil, 0, fixtureProject("p1", []string{"en"}, map[string]map[string]string{"header_1": {}})...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another translator saves between the command reading the project and its events being written
+	racingUpdate := func(ctx context.Context, input UpdateTranslationInput) ([]Event, error) {
+		events, err := UpdateTranslation(eventStore)(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		err = eventStore.Write(ctx, nil, AnyVersion, TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hi"})
+		return events, err
+	}
+	updateTranslation := NewCommandPipeline(db, eventStore, racingUpdate)
+	err = updateTranslation(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello"}, AnyVersion)
+	var conflict ErrConcurrencyConflict
+	if !errors.As(err, &conflict) || conflict.ExpectedVersion != 2 || conflict.ActualVersion != 3 {
+		t.Fatalf("expected a conflict with the version the command read, got %v", err)
+	}
+
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := project.KeysById["header_1"].TranslationsById["en"].Value; value != "Hi" {
+		t.Errorf("expected the first save to be kept, got %q", value)
+	}
+
+	// without anyone in between the same command goes through
+	updateTranslation = NewCommandPipeline(db, eventStore, UpdateTranslation(eventStore))
+	err = updateTranslation(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello"}, AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommandPipelineConcurrencyConflictPerAggregate(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+	err := eventStore.Write(ctx, nil, 0, fixtureProject("p1", []string{"en"}, map[string]map[string]string{"header_1": {"en": "Hello"}})...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eventStore.Write(ctx, nil, 0, fixtureProject("p2", []string{"en"}, nil)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// someone adds a key to the destination project between the move reading it and its events being written
+	racingMove := func(ctx context.Context, input MoveKeyInput) ([]Event, error) {
+		events, err := MoveKey(eventStore)(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		err = eventStore.Write(ctx, nil, AnyVersion, KeyCreated{EventBase: NewEventBase(ctx, "p2"), ProjectId: "p2", Id: "header_2"})
+		return events, err
+	}
+	moveKey := NewCommandPipeline(db, eventStore, racingMove)
+	err = moveKey(ctx, MoveKeyInput{ProjectId: "p1", Id: "header_1", ToProjectId: "p2"}, AnyVersion)
+	var conflict ErrConcurrencyConflict
+	if !errors.As(err, &conflict) || conflict.AggregateId != "p2" || conflict.ExpectedVersion != 1 || conflict.ActualVersion != 2 {
+		t.Fatalf("expected a conflict on the destination project, got %v", err)
+	}
+
+	// the source project's half of the move is rolled back with it
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := project.KeysById["header_1"]; !ok {
+		t.Errorf("expected header_1 to still be in p1")
+	}
+}
+
+func TestCommandPipelineInterleavedAggregates(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+	err := eventStore.Write(ctx, nil, 0, fixtureProject("p1", []string{"en"}, nil)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eventStore.Write(ctx, nil, 0, fixtureProject("p2", []string{"en"}, nil)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reads both projects and writes p1, p2 and then p1 again, each project is still only checked once
+	createKeys := func(ctx context.Context, keyId string) ([]Event, error) {
+		for _, projectId := range []string{"p1", "p2"} {
+			_, err := GetProject(ctx, eventStore, projectId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []Event{
+			KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: keyId},
+			KeyCreated{EventBase: NewEventBase(ctx, "p2"), ProjectId: "p2", Id: keyId},
+			TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: keyId, Id: "en", Value: "Hello"},
+		}, nil
+	}
+	err = NewCommandPipeline(db, eventStore, createKeys)(ctx, "header_1", AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Version != 3 || project.Resolve("header_1", "en").Value != "Hello" {
+		t.Errorf("expected both of p1's events in order, got version %d %+v", project.Version, project.KeysById)
+	}
+}
+
+func TestRegisterUserConcurrently(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+
+	// every registration checks the username is free before any of them is written
+	const registrations = 5
+	var checked sync.WaitGroup
+	checked.Add(registrations)
+	racingRegister := func(ctx context.Context, input RegisterUserInput) ([]Event, error) {
+		events, err := RegisterUser(eventStore)(ctx, input)
+		checked.Done()
+		checked.Wait()
+		return events, err
+	}
+	registerUser := NewCommandPipeline(db, eventStore, racingRegister)
+
+	errs := make(chan error, registrations)
+	for i := 0; i < registrations; i++ {
+		go func() {
+			errs <- registerUser(ctx, RegisterUserInput{Username: "alice", Password: fmt.Sprintf("secret12%d", i)}, AnyVersion)
+		}()
+	}
+	registered := 0
+	for i := 0; i < registrations; i++ {
+		err := <-errs
+		var conflict ErrConcurrencyConflict
+		switch {
+		case err == nil:
+			registered++
+		case !errors.As(err, &conflict):
+			t.Errorf("expected a conflict for every other registration, got %v", err)
+		}
+	}
+	if registered != 1 {
+		t.Errorf("expected one registration to go through, got %d", registered)
+	}
+
+	users, err := GetUsers(ctx, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users.UsersById) != 1 {
+		t.Errorf("expected alice to be registered once, got %d users", len(users.UsersById))
+	}
+
+	// later registrations are checked against every registration before them
+	err = NewCommandPipeline(db, eventStore, RegisterUser(eventStore))(ctx, RegisterUserInput{Username: "bob", Password: "secret123"}, AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingReadModel fails on the first event of the type it's given, like a read model with a broken query
+type failingReadModel struct {
+	typeName string
+}
+
+func (o failingReadModel) Handle(ctx context.Context, tx *sql.Tx, event Event) error {
+	if TypeName(event) == o.typeName {
+		return errors.New("read model failed")
+	}
+	return nil
+}
+
+func TestCommandPipelineAtomic(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+	err := eventStore.Write(ctx, nil, 0, fixtureProject("p1", []string{"en", "es"}, nil)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the read model fails on the first value, once KeyCreated has already been written and handled
+	createKey := NewCommandPipeline(db, eventStore, CreateKey(eventStore), failingReadModel{typeName: "TranslationUpdated"})
+	err = createKey(ctx, CreateKeyInput{ProjectId: "p1", Id: "header_1", Values: map[string]string{"en": "Hello", "es": "Hola"}}, AnyVersion)
+	if err == nil {
+		t.Fatal("expected the read model's error")
+	}
+
+	event, err := eventStore.NewGenerator(AfterPosition(1)).Next(ctx)
+	if err != nil || event != nil {
+		t.Errorf("expected none of the key's events to be written, got %v, %v", event, err)
+	}
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.KeysById) != 0 || project.Version != 1 {
+		t.Errorf("expected the project to be untouched, got version %d with %v", project.Version, project.KeysById)
+	}
+}
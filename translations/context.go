@@ -0,0 +1,107 @@
+package translations
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+/*
+Context
+- request scoped values that NewEventBase copies onto every event it creates
+*/
+type contextKey string
+
+const (
+	actorKey         contextKey = "actor"
+	correlationIdKey contextKey = "correlationId"
+	causationIdKey   contextKey = "causationId"
+	metadataKey      contextKey = "metadata"
+	readVersionsKey  contextKey = "readVersions"
+)
+
+// WithCorrelationId groups every event created with the returned context, e.g. everything done by one request
+func WithCorrelationId(ctx context.Context, correlationId string) context.Context {
+	return context.WithValue(ctx, correlationIdKey, correlationId)
+}
+
+func GetCorrelationId(ctx context.Context) string {
+	correlationId, _ := ctx.Value(correlationIdKey).(string)
+	return correlationId
+}
+
+// WithCausationId records what directly caused the events created with the returned context, e.g. a request or another event
+func WithCausationId(ctx context.Context, causationId string) context.Context {
+	return context.WithValue(ctx, causationIdKey, causationId)
+}
+
+func GetCausationId(ctx context.Context) string {
+	causationId, _ := ctx.Value(causationIdKey).(string)
+	return causationId
+}
+
+// WithMetadata adds a key value pair to the metadata of events created with the returned context
+func WithMetadata(ctx context.Context, key string, value string) context.Context {
+	metadata := maps.Clone(GetMetadata(ctx))
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata[key] = value
+	return context.WithValue(ctx, metadataKey, metadata)
+}
+
+func GetMetadata(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataKey).(map[string]string)
+	return metadata
+}
+
+// WithRequestId correlates events with the HTTP request that caused them, keeping any correlation id already set
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	if GetCorrelationId(ctx) == "" {
+		ctx = WithCorrelationId(ctx, requestId)
+	}
+	ctx = WithCausationId(ctx, requestId)
+	return WithMetadata(ctx, "requestId", requestId)
+}
+
+// WithCausingEvent is for events created in reaction to another, they share its correlation id and are caused by it
+func WithCausingEvent(ctx context.Context, event Event) context.Context {
+	ctx = WithCorrelationId(ctx, event.GetCorrelationId())
+	return WithCausationId(ctx, event.GetEventId())
+}
+
+// readVersions are the versions of the aggregates a command read, what its events were decided against
+type readVersions struct {
+	mu                    sync.Mutex
+	versionsByAggregateId map[string]int
+}
+
+// withReadVersions starts recording the versions of the aggregates read with the returned context
+func withReadVersions(ctx context.Context) (context.Context, *readVersions) {
+	versions := &readVersions{versionsByAggregateId: map[string]int{}}
+	return context.WithValue(ctx, readVersionsKey, versions), versions
+}
+
+// recordReadVersion keeps the first version of an aggregate that was read, a later read can't hide a change since
+func recordReadVersion(ctx context.Context, aggregateId string, version int) {
+	versions, ok := ctx.Value(readVersionsKey).(*readVersions)
+	if !ok {
+		return
+	}
+	versions.mu.Lock()
+	defer versions.mu.Unlock()
+	if _, ok := versions.versionsByAggregateId[aggregateId]; !ok {
+		versions.versionsByAggregateId[aggregateId] = version
+	}
+}
+
+// get returns the version aggregateId was read at, AnyVersion if it wasn't
+func (o *readVersions) get(aggregateId string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	version, ok := o.versionsByAggregateId[aggregateId]
+	if !ok {
+		return AnyVersion
+	}
+	return version
+}
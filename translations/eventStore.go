@@ -3,57 +3,139 @@ package translations
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
-	"errors"
-	"reflect"
+	"fmt"
+	"slices"
+	"sync"
 )
 
+/*
+EventStore
+- Write appends events to their aggregates' streams, all or nothing, as part of tx when one is given
+- InMemoryEventStore has no transactions and ignores tx, its events stay written when the rest of tx is rolled back,
+so NewCommandPipeline is only all or nothing with read models on a store that writes through tx like SQLiteEventStore
+- events are written grouped by aggregate, in the order each aggregate first appears, keeping the order of each aggregate's events
+- expectedVersion is the version of the first event's stream the events were decided against, use AnyVersion to skip the check,
+it's checked once and only for that stream, to check several write each aggregate's events with its own version like NewCommandPipeline
+- Subscribe returns every event after fromPosition, waiting for new ones once it has caught up
+*/
 type EventStore interface {
-	Write(ctx context.Context, event Event) error
+	Write(ctx context.Context, tx *sql.Tx, expectedVersion int, events ...Event) error
 	NewGenerator(queryOptions ...QueryOption) GeneratorFn
+	Subscribe(ctx context.Context, fromPosition int64, queryOptions ...QueryOption) GeneratorFn
+}
+
+// AnyVersion can be passed as an expected version to write regardless of the stream's current version
+const AnyVersion = -1
+
+type ErrConcurrencyConflict struct {
+	AggregateId     string
+	ExpectedVersion int
+	ActualVersion   int
+}
+
+func (o ErrConcurrencyConflict) Error() string {
+	return fmt.Sprintf("concurrency conflict on aggregate %s: expected version %d but stream is at version %d", o.AggregateId, o.ExpectedVersion, o.ActualVersion)
+}
+
+// groupByAggregate splits events by aggregate, in the order each aggregate first appears, keeping the order of each one's events
+func groupByAggregate(events []Event) [][]Event {
+	groups := [][]Event{}
+	indexByAggregateId := map[string]int{}
+	for _, event := range events {
+		i, ok := indexByAggregateId[event.GetAggregateId()]
+		if !ok {
+			i = len(groups)
+			indexByAggregateId[event.GetAggregateId()] = i
+			groups = append(groups, []Event{})
+		}
+		groups[i] = append(groups[i], event)
+	}
+	return groups
+}
+
+func checkVersion(aggregateId string, expectedVersion int, actualVersion int) error {
+	if expectedVersion == AnyVersion || expectedVersion == actualVersion {
+		return nil
+	}
+	return ErrConcurrencyConflict{
+		AggregateId:     aggregateId,
+		ExpectedVersion: expectedVersion,
+		ActualVersion:   actualVersion,
+	}
 }
 
 type InMemoryEventStore struct {
-	events []Event
+	notifier
+
+	mu                    sync.RWMutex
+	events                []Event
+	versionsByAggregateId map[string]int
+	snapshotsById         map[string]Snapshot
 }
 
+// NewInMemoryEventStore returns a store with a demo project in it
 func NewInMemoryEventStore() *InMemoryEventStore {
-	return &InMemoryEventStore{
-		events: []Event{
-			ProjectCreated{
-				EventBase: NewEventBase(context.Background(), "asdf"),
-				Id:        "asdf",
-				Name:      "Test Project",
-			},
-			KeyCreated{
-				EventBase: NewEventBase(context.Background(), "asdf"),
-				ProjectId: "asdf",
-				Id:        "header_1",
-			},
-			TranslationUpdated{
-				EventBase: NewEventBase(context.Background(), "asdf"),
-				ProjectId: "asdf",
-				KeyId:     "header_1",
-				Id:        "en",
-				Value:     "Hello",
-			},
-			TranslationUpdated{
-				EventBase: NewEventBase(context.Background(), "asdf"),
-				ProjectId: "asdf",
-				KeyId:     "header_1",
-				Id:        "es",
-				Value:     "Hola",
-			},
+	o := newInMemoryEventStore()
+	seed := []Event{
+		ProjectCreated{
+			EventBase: NewEventBase(context.Background(), "asdf"),
+			Id:        "asdf",
+			Name:      "Test Project",
+			Locales:   []string{"es", "en"},
+		},
+		KeyCreated{
+			EventBase: NewEventBase(context.Background(), "asdf"),
+			ProjectId: "asdf",
+			Id:        "header_1",
+		},
+		TranslationUpdated{
+			EventBase: NewEventBase(context.Background(), "asdf"),
+			ProjectId: "asdf",
+			KeyId:     "header_1",
+			Id:        "en",
+			Value:     "Hello",
+		},
+		TranslationUpdated{
+			EventBase: NewEventBase(context.Background(), "asdf"),
+			ProjectId: "asdf",
+			KeyId:     "header_1",
+			Id:        "es",
+			Value:     "Hola",
 		},
 	}
+	o.Write(context.Background(), nil, AnyVersion, seed...)
+	return o
 }
 
-func (o *InMemoryEventStore) Handle(ctx context.Context, tx *sql.Tx, event Event) error {
-	return o.Write(ctx, event)
+func newInMemoryEventStore() *InMemoryEventStore {
+	return &InMemoryEventStore{
+		versionsByAggregateId: map[string]int{},
+		snapshotsById:         map[string]Snapshot{},
+	}
 }
 
-func (o *InMemoryEventStore) Write(ctx context.Context, event Event) error {
-	o.events = append(o.events, event)
+// Write ignores tx, events are visible as soon as they're written and aren't undone if tx is rolled back
+func (o *InMemoryEventStore) Write(ctx context.Context, tx *sql.Tx, expectedVersion int, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	defer o.notify()
+
+	aggregateId := events[0].GetAggregateId()
+	err := checkVersion(aggregateId, expectedVersion, o.versionsByAggregateId[aggregateId])
+	if err != nil {
+		return err
+	}
+
+	for _, event := range slices.Concat(groupByAggregate(events)...) {
+		aggregateId := event.GetAggregateId()
+		o.versionsByAggregateId[aggregateId]++
+		position := int64(len(o.events) + 1)
+		o.events = append(o.events, withPosition(event, position, o.versionsByAggregateId[aggregateId]))
+	}
 	return nil
 }
 
@@ -63,20 +145,62 @@ func (o *InMemoryEventStore) NewGenerator(queryOptions ...QueryOption) Generator
 		opt(&query)
 	}
 
-	current := 0
+	// positions start at 1, so the event at index i has position i+1
+	current := int(query.AfterPosition)
+	returned := 0
 	return func(ctx context.Context) (Event, error) {
+		o.mu.RLock()
+		defer o.mu.RUnlock()
+
+		if query.Limit > 0 && returned >= query.Limit {
+			return nil, nil
+		}
+
 		for i := current; i < len(o.events); i++ {
 			current++
 			if query.AggregateIds != nil && !Contains(query.AggregateIds, o.events[i].GetAggregateId()) {
 				continue
 			}
+			if query.Types != nil && !Contains(query.Types, TypeName(o.events[i])) {
+				continue
+			}
+			if query.CorrelationIds != nil && !Contains(query.CorrelationIds, o.events[i].GetCorrelationId()) {
+				continue
+			}
+			if query.CausationIds != nil && !Contains(query.CausationIds, o.events[i].GetCausationId()) {
+				continue
+			}
 
+			returned++
 			return o.events[i], nil
 		}
 		return nil, nil
 	}
 }
 
+func (o *InMemoryEventStore) Subscribe(ctx context.Context, fromPosition int64, queryOptions ...QueryOption) GeneratorFn {
+	return subscribe(ctx, o, &o.notifier, fromPosition, queryOptions)
+}
+
+func (o *InMemoryEventStore) LoadSnapshot(ctx context.Context, aggregateId string) (*Snapshot, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	snapshot, ok := o.snapshotsById[aggregateId]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+func (o *InMemoryEventStore) SaveSnapshot(ctx context.Context, snapshot Snapshot) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.snapshotsById[snapshot.AggregateId] = snapshot
+	return nil
+}
+
 func Contains[T comparable](tt []T, t T) bool {
 	for _, elem := range tt {
 		if elem == t {
@@ -87,8 +211,12 @@ func Contains[T comparable](tt []T, t T) bool {
 }
 
 type Query struct {
-	AggregateIds []string
-	Types        []string
+	AggregateIds   []string
+	Types          []string
+	CorrelationIds []string
+	CausationIds   []string
+	AfterPosition  int64
+	Limit          int
 }
 
 type QueryOption func(query *Query)
@@ -99,50 +227,35 @@ func AggregateIds(aggregateIds ...string) QueryOption {
 	}
 }
 
-type TypeWrapper struct {
-	TypeName string `json:"typeName"`
-	Payload  string `json:"payload"`
+// EventTypes only returns events of the given types, by the names they're registered under (see TypeName)
+func EventTypes(types ...string) QueryOption {
+	return func(query *Query) {
+		query.Types = types
+	}
 }
 
-func Serialize(data any) (string, error) {
-	serializedPayload, err := json.Marshal(data)
-	if err != nil {
-		return "", err
+func CorrelationIds(correlationIds ...string) QueryOption {
+	return func(query *Query) {
+		query.CorrelationIds = correlationIds
 	}
+}
 
-	serializedTypeWrapper, err := json.Marshal(TypeWrapper{
-		TypeName: reflect.TypeOf(data).String(),
-		Payload:  string(serializedPayload),
-	})
-	if err != nil {
-		return "", err
+func CausationIds(causationIds ...string) QueryOption {
+	return func(query *Query) {
+		query.CausationIds = causationIds
 	}
-	return string(serializedTypeWrapper), nil
 }
 
-var ErrorNoTypeMatch = errors.New("no type match found in targets")
-
-func Deserialize(data string, targets ...any) (any, error) {
-	var typeWrapper TypeWrapper
-	err := json.Unmarshal([]byte(data), &typeWrapper)
-	if err != nil {
-		return nil, err
+// AfterPosition only returns events written after the given position, use it to resume reading
+func AfterPosition(position int64) QueryOption {
+	return func(query *Query) {
+		query.AfterPosition = position
 	}
+}
 
-	for _, target := range targets {
-		rTargetType := reflect.TypeOf(target)
-		if typeWrapper.TypeName != rTargetType.String() {
-			continue
-		}
-
-		rTargetPtr := reflect.New(rTargetType)                                     // this is like t := &T{}
-		err := json.Unmarshal([]byte(typeWrapper.Payload), rTargetPtr.Interface()) // this is like t
-		if err != nil {
-			return nil, err
-		}
-
-		return rTargetPtr.Elem().Interface(), nil // this is like *t
+// Limit caps the number of events a generator will return, 0 means no limit
+func Limit(limit int) QueryOption {
+	return func(query *Query) {
+		query.Limit = limit
 	}
-
-	return nil, ErrorNoTypeMatch
 }
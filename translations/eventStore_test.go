@@ -1,7 +1,11 @@
 package translations
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -14,16 +18,20 @@ type Struct2 struct {
 }
 
 func TestSerialization(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister("Struct1", Struct1{})
+	registry.MustRegister("Struct2", Struct2{})
+
 	s := Struct1{
 		Message: "hello",
 	}
 
-	serialized, err := Serialize(s)
+	serialized, err := registry.Serialize(s)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	deserialized, err := Deserialize(serialized, Struct1{}, Struct2{})
+	deserialized, err := registry.Deserialize(serialized)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -42,3 +50,236 @@ func TestSerialization(t *testing.T) {
 		t.Fatal("no type match")
 	}
 }
+
+// Greeting started out as {Text string}, then Text was renamed to Message, then Language was added
+type Greeting struct {
+	Message  string `json:"message"`
+	Language string `json:"language"`
+}
+
+func TestRegistryErrors(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister("Struct1", Struct1{}, "translations.Struct1")
+
+	err := registry.Register("Struct1", Struct2{})
+	if !errors.Is(err, ErrorDuplicateName) {
+		t.Errorf("expected ErrorDuplicateName, got %v", err)
+	}
+	err = registry.Register("Other", Struct1{})
+	if !errors.Is(err, ErrorDuplicateType) {
+		t.Errorf("expected ErrorDuplicateType, got %v", err)
+	}
+
+	_, err = registry.Serialize(Struct2{})
+	if !errors.Is(err, ErrorUnregistered) {
+		t.Errorf("expected ErrorUnregistered, got %v", err)
+	}
+	_, err = registry.Deserialize(`{"typeName":"Struct2","payload":"{}"}`)
+	if !errors.Is(err, ErrorUnknownName) {
+		t.Errorf("expected ErrorUnknownName, got %v", err)
+	}
+
+	// aliases resolve to the registered type
+	deserialized, err := registry.Deserialize(`{"typeName":"translations.Struct1","payload":"{\"message\":\"hi\"}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deserialized.(Struct1).Message != "hi" {
+		t.Errorf("alias deserialized to %+v", deserialized)
+	}
+}
+
+func TestUpcasting(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister("Greeting", Greeting{})
+
+	typeName := "Greeting"
+	err := registry.RegisterUpcaster(typeName, 1, func(payload map[string]any) (map[string]any, error) {
+		payload["message"] = payload["text"]
+		delete(payload, "text")
+		return payload, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterUpcaster(typeName, 2, func(payload map[string]any) (map[string]any, error) {
+		payload["language"] = "en"
+		return payload, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = registry.RegisterUpcaster(typeName, 2, func(payload map[string]any) (map[string]any, error) {
+		return payload, nil
+	})
+	if err == nil {
+		t.Error("expected registering an upcaster out of order to fail")
+	}
+
+	// written before schema versions existed
+	v1 := `{"typeName":"Greeting","payload":"{\"text\":\"hello\"}"}`
+	deserialized, err := registry.Deserialize(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if greeting := deserialized.(Greeting); greeting.Message != "hello" || greeting.Language != "en" {
+		t.Errorf("v1 payload upcast to %+v", greeting)
+	}
+
+	v2 := `{"typeName":"Greeting","version":2,"payload":"{\"message\":\"hola\"}"}`
+	deserialized, err = registry.Deserialize(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if greeting := deserialized.(Greeting); greeting.Message != "hola" || greeting.Language != "en" {
+		t.Errorf("v2 payload upcast to %+v", greeting)
+	}
+
+	// current payloads are written at the latest version and read back untouched
+	serialized, err := registry.Serialize(Greeting{Message: "bonjour", Language: "fr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(serialized, `"version":3`) {
+		t.Errorf("expected version 3 in %s", serialized)
+	}
+	deserialized, err = registry.Deserialize(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if greeting := deserialized.(Greeting); greeting.Message != "bonjour" || greeting.Language != "fr" {
+		t.Errorf("current payload read back as %+v", greeting)
+	}
+
+	v4 := `{"typeName":"Greeting","version":4,"payload":"{}"}`
+	_, err = registry.Deserialize(v4)
+	if err == nil {
+		t.Error("expected a payload from a newer schema version to fail")
+	}
+}
+
+func TestEventUpcasters(t *testing.T) {
+	// stored before locales were configurable and before review existed
+	var project Project
+	for _, stored := range []string{
+		`{"typeName":"ProjectCreated","payload":"{\"Id\":\"p2\",\"Name\":\"Project 2\"}"}`,
+		`{"typeName":"KeyMoved","payload":"{\"Id\":\"header_1\",\"FromProjectId\":\"p1\",\"ToProjectId\":\"p2\",\"Values\":{\"en\":\"Hello\",\"es\":\"\"}}"}`,
+	} {
+		event, err := DefaultRegistry.Deserialize(stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		project.Reduce(event.(Event))
+	}
+
+	if !slices.Equal(project.Locales, legacyLocales) || project.SourceLocale != "es" {
+		t.Errorf("expected the legacy locales, got %v with source %s", project.Locales, project.SourceLocale)
+	}
+	translations := project.KeysById["header_1"].TranslationsById
+	if translations["en"].Status != StatusDraft || translations["es"].Status != StatusUntranslated {
+		t.Errorf("expected en to be a draft and es untranslated, got %s and %s", translations["en"].Status, translations["es"].Status)
+	}
+}
+
+func TestEventTypes(t *testing.T) {
+	for name, newEventStore := range map[string]func(t *testing.T) EventStore{
+		"in memory": func(t *testing.T) EventStore { return newTestEventStore(t) },
+		"sqlite": func(t *testing.T) EventStore {
+			_, eventStore := newTestSQLiteEventStore(t)
+			return eventStore
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			eventStore := newEventStore(t)
+			err := eventStore.Write(ctx, nil, AnyVersion,
+				ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1"},
+				KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: "header_1"},
+				TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello"},
+				ProjectUpdated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "Renamed"},
+				ProjectCreated{EventBase: NewEventBase(ctx, "p2"), Id: "p2"},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			types := []string{}
+			generator := eventStore.NewGenerator(EventTypes("ProjectCreated", "ProjectUpdated"), AggregateIds("p1", "p2"))
+			for {
+				event, err := generator.Next(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if event == nil {
+					break
+				}
+				types = append(types, TypeName(event)+" "+event.GetAggregateId())
+			}
+			expected := []string{"ProjectCreated p1", "ProjectUpdated p1", "ProjectCreated p2"}
+			if !slices.Equal(types, expected) {
+				t.Errorf("expected %v, got %v", expected, types)
+			}
+
+			generator = eventStore.NewGenerator(EventTypes("TranslationUpdated"))
+			for {
+				event, err := generator.Next(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if event == nil {
+					break
+				}
+				if _, ok := event.(TranslationUpdated); !ok {
+					t.Errorf("expected only TranslationUpdated events, got %T", event)
+				}
+			}
+
+			event, err := eventStore.NewGenerator(EventTypes("KeyDeleted")).Next(ctx)
+			if err != nil || event != nil {
+				t.Errorf("expected no events of a type that was never written, got %v, %v", event, err)
+			}
+		})
+	}
+}
+
+func TestWriteInterleavedAggregates(t *testing.T) {
+	for name, newEventStore := range map[string]func(t *testing.T) EventStore{
+		"in memory": func(t *testing.T) EventStore { return newTestEventStore(t) },
+		"sqlite": func(t *testing.T) EventStore {
+			_, eventStore := newTestSQLiteEventStore(t)
+			return eventStore
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			eventStore := newEventStore(t)
+			err := eventStore.Write(ctx, nil, 0,
+				ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1"},
+				ProjectCreated{EventBase: NewEventBase(ctx, "p2"), Id: "p2"},
+				KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: "header_1"},
+				KeyCreated{EventBase: NewEventBase(ctx, "p2"), ProjectId: "p2", Id: "header_1"},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			written := []string{}
+			generator := eventStore.NewGenerator()
+			for {
+				event, err := generator.Next(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if event == nil {
+					break
+				}
+				written = append(written, fmt.Sprintf("%s %s %d", TypeName(event), event.GetAggregateId(), event.GetVersion()))
+			}
+			expected := []string{"ProjectCreated p1 1", "KeyCreated p1 2", "ProjectCreated p2 1", "KeyCreated p2 2"}
+			if !slices.Equal(written, expected) {
+				t.Errorf("expected the events grouped by aggregate %v, got %v", expected, written)
+			}
+		})
+	}
+}
@@ -2,35 +2,80 @@ package translations
 
 import (
 	"context"
+	"maps"
+	"reflect"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Event interface {
+	GetEventId() string
 	GetActor() string
 	GetAggregateId() string
 	GetTimestamp() time.Time
+	GetPosition() int64
+	GetVersion() int
+	GetCorrelationId() string
+	GetCausationId() string
+	GetMetadata() map[string]string
 }
 
 type EventBase struct {
+	EventId     string    // not Id, events have their own Id fields for the thing they're about
 	Actor       string    // who
 	AggregateId string    // what (this aggregates into)
 	Timestamp   time.Time // when
+
+	// why, see WithCorrelationId and WithCausationId
+	CorrelationId string
+	CausationId   string
+	Metadata      map[string]string `json:",omitempty"`
+
+	// assigned by the event store on write
+	Position int64 // order across all events
+	Version  int   // order within the aggregate
 }
 
 /*
 Consider - should aggregateType be set?
 */
 func NewEventBase(ctx context.Context, aggregateId string) EventBase {
+	id := uuid.NewString()
+	correlationId := GetCorrelationId(ctx)
+	if correlationId == "" {
+		correlationId = id
+	}
+
 	return EventBase{
-		Actor:       GetActor(ctx),
-		AggregateId: aggregateId,
-		Timestamp:   time.Now(),
+		EventId:       id,
+		Actor:         GetActor(ctx),
+		AggregateId:   aggregateId,
+		Timestamp:     time.Now(),
+		CorrelationId: correlationId,
+		CausationId:   GetCausationId(ctx),
+		Metadata:      maps.Clone(GetMetadata(ctx)),
 	}
 }
 
+// SystemActor is who events are attributed to when they weren't caused by a user, e.g. seed data
+const SystemActor = "system"
+
+// WithActor attributes events created with the returned context to a user
+func WithActor(ctx context.Context, userId string) context.Context {
+	return context.WithValue(ctx, actorKey, userId)
+}
+
 func GetActor(ctx context.Context) string {
-	//TODO
-	return "123"
+	actor, ok := ctx.Value(actorKey).(string)
+	if !ok {
+		return SystemActor
+	}
+	return actor
+}
+
+func (o EventBase) GetEventId() string {
+	return o.EventId
 }
 
 func (o EventBase) GetActor() string {
@@ -45,14 +90,92 @@ func (o EventBase) GetTimestamp() time.Time {
 	return o.Timestamp
 }
 
+func (o EventBase) GetPosition() int64 {
+	return o.Position
+}
+
+func (o EventBase) GetVersion() int {
+	return o.Version
+}
+
+func (o EventBase) GetCorrelationId() string {
+	return o.CorrelationId
+}
+
+func (o EventBase) GetCausationId() string {
+	return o.CausationId
+}
+
+func (o EventBase) GetMetadata() map[string]string {
+	return o.Metadata
+}
+
+// withPosition returns a copy of event with its store assigned position and version set
+func withPosition(event Event, position int64, version int) Event {
+	rEventPtr := reflect.New(reflect.TypeOf(event)) // this is like e := &E{}
+	rEventPtr.Elem().Set(reflect.ValueOf(event))    // this is like *e = event
+	eventBase := rEventPtr.Elem().FieldByName("EventBase").Addr().Interface().(*EventBase)
+	eventBase.Position = position
+	eventBase.Version = version
+	return rEventPtr.Elem().Interface().(Event)
+}
+
 /*
 Events
 - describes a thing that happened at a specific time
+- every event is registered in DefaultRegistry under a name that must never change, it's how they're stored
 */
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	for name, prototype := range map[string]any{
+		"ProjectCreated":           ProjectCreated{},
+		"ProjectUpdated":           ProjectUpdated{},
+		"ProjectDeleted":           ProjectDeleted{},
+		"LocaleAdded":              LocaleAdded{},
+		"LocaleRemoved":            LocaleRemoved{},
+		"SourceLocaleChanged":      SourceLocaleChanged{},
+		"FallbacksChanged":         FallbacksChanged{},
+		"KeyCreated":               KeyCreated{},
+		"KeyDeleted":               KeyDeleted{},
+		"KeyRenamed":               KeyRenamed{},
+		"KeyMoved":                 KeyMoved{},
+		"KeyMetadataUpdated":       KeyMetadataUpdated{},
+		"KeyPluralChanged":         KeyPluralChanged{},
+		"TranslationUpdated":       TranslationUpdated{},
+		"TranslationDeleted":       TranslationDeleted{},
+		"PluralTranslationUpdated": PluralTranslationUpdated{},
+		"TranslationSubmitted":     TranslationSubmitted{},
+		"TranslationApproved":      TranslationApproved{},
+		"TranslationRejected":      TranslationRejected{},
+		"CommentAdded":             CommentAdded{},
+		"CommentEdited":            CommentEdited{},
+		"CommentResolved":          CommentResolved{},
+		"QARuleEnabled":            QARuleEnabled{},
+		"QARuleDisabled":           QARuleDisabled{},
+		"GlossaryTermAdded":        GlossaryTermAdded{},
+		"GlossaryTermUpdated":      GlossaryTermUpdated{},
+		"GlossaryTermRemoved":      GlossaryTermRemoved{},
+		"UserRegistered":           UserRegistered{},
+		"ApiTokenIssued":           ApiTokenIssued{},
+		"ApiTokenRevoked":          ApiTokenRevoked{},
+
+		// not an event, but serialized into snapshots
+		"Project": Project{},
+	} {
+		// events written before the registry existed were named after their Go type
+		registry.MustRegister(name, prototype, "translations."+name)
+	}
+	registerEventUpcasters(registry)
+	return registry
+}
+
 type ProjectCreated struct {
 	EventBase
-	Id   string
-	Name string
+	Id      string
+	Name    string
+	Locales []string
 }
 
 type ProjectUpdated struct {
@@ -66,10 +189,62 @@ type ProjectDeleted struct {
 	Id string
 }
 
+type LocaleAdded struct {
+	EventBase
+	ProjectId string
+	Locale    string
+}
+
+type LocaleRemoved struct {
+	EventBase
+	ProjectId string
+	Locale    string
+}
+
+type SourceLocaleChanged struct {
+	EventBase
+	ProjectId string
+	Locale    string
+}
+
+// FallbacksChanged replaces the locales Locale falls back to, in order, an empty list restores the default
+type FallbacksChanged struct {
+	EventBase
+	ProjectId string
+	Locale    string
+	Fallbacks []string
+}
+
 type KeyCreated struct {
 	EventBase
 	Id        string
 	ProjectId string
+	Plural    bool `json:",omitempty"`
+	KeyMetadata
+}
+
+// KeyMetadata is what translators are told about a key
+type KeyMetadata struct {
+	Description string   `json:",omitempty"` // what the key means
+	Notes       string   `json:",omitempty"` // from developers, e.g. where it's shown
+	MaxLength   int      `json:",omitempty"` // in characters, 0 for no limit
+	Tags        []string `json:",omitempty"`
+}
+
+// KeyPluralChanged marks a key as having a value per plural category, or back to a single value, which is kept as the other category
+type KeyPluralChanged struct {
+	EventBase
+	Id        string
+	ProjectId string
+	Plural    bool
+}
+
+// KeyMetadataUpdated replaces all of a key's metadata
+type KeyMetadataUpdated struct {
+	EventBase
+	Id        string
+	ProjectId string
+	KeyMetadata
 }
 
 type KeyDeleted struct {
@@ -78,6 +253,28 @@ type KeyDeleted struct {
 	ProjectId string
 }
 
+// KeyRenamed gives a key a new id, its translations and history go with it
+type KeyRenamed struct {
+	EventBase
+	Id        string
+	ProjectId string
+	NewId     string
+}
+
+// KeyMoved is written to both projects, the key leaves FromProjectId and arrives in ToProjectId with its values
+type KeyMoved struct {
+	EventBase
+	Id            string
+	FromProjectId string
+	ToProjectId   string
+	DateCreated   time.Time
+	Values        map[string]string                    // by locale
+	Statuses      map[string]TranslationStatus         `json:",omitempty"` // by locale, of the locales with a value
+	Plural        bool                                 `json:",omitempty"`
+	Plurals       map[string]map[PluralCategory]string `json:",omitempty"` // by locale
+	KeyMetadata
+}
+
 // type TranslationCreated struct {
 // 	EventBase
 // 	Id        string
@@ -100,3 +297,125 @@ type TranslationDeleted struct {
 	KeyId     string
 	ProjectId string
 }
+
+// PluralTranslationUpdated replaces every plural variant of a translation of a plural key
+type PluralTranslationUpdated struct {
+	EventBase
+	Id        string
+	KeyId     string
+	ProjectId string
+	Plurals   map[PluralCategory]string
+}
+
+// translations are submitted for review by their translator, then approved or rejected by a reviewer
+type TranslationSubmitted struct {
+	EventBase
+	Id        string
+	KeyId     string
+	ProjectId string
+}
+
+type TranslationApproved struct {
+	EventBase
+	Id        string
+	KeyId     string
+	ProjectId string
+}
+
+type TranslationRejected struct {
+	EventBase
+	Id        string
+	KeyId     string
+	ProjectId string
+	Reason    string
+}
+
+// comments are on the project's stream, KeyId and Locale narrow down what they're about when they're set
+type CommentAdded struct {
+	EventBase
+	Id        string
+	ThreadId  string
+	ProjectId string
+	KeyId     string
+	Locale    string
+	Text      string
+}
+
+type CommentEdited struct {
+	EventBase
+	Id        string
+	ThreadId  string
+	ProjectId string
+	Text      string
+}
+
+// CommentResolved closes a whole thread, comments after it start a new one
+type CommentResolved struct {
+	EventBase
+	ThreadId  string
+	ProjectId string
+}
+
+// QA rules are enabled in every project until they're disabled, Rule is the name of a QARule
+type QARuleEnabled struct {
+	EventBase
+	ProjectId string
+	Rule      string
+}
+
+type QARuleDisabled struct {
+	EventBase
+	ProjectId string
+	Rule      string
+}
+
+// GlossaryEntry is what a project's glossary says about a term of the source locale
+type GlossaryEntry struct {
+	Term           string
+	Description    string            `json:",omitempty"`
+	DoNotTranslate bool              `json:",omitempty"` // the term stays as it is in every locale, e.g. a brand name
+	Translations   map[string]string `json:",omitempty"` // approved translations by locale
+}
+
+type GlossaryTermAdded struct {
+	EventBase
+	Id        string
+	ProjectId string
+	GlossaryEntry
+}
+
+// GlossaryTermUpdated replaces everything about a glossary term
+type GlossaryTermUpdated struct {
+	EventBase
+	Id        string
+	ProjectId string
+	GlossaryEntry
+}
+
+type GlossaryTermRemoved struct {
+	EventBase
+	Id        string
+	ProjectId string
+}
+
+// users aggregate on their own id, passwords and tokens are only ever stored hashed
+type UserRegistered struct {
+	EventBase
+	Id           string
+	Username     string
+	PasswordHash string
+}
+
+type ApiTokenIssued struct {
+	EventBase
+	Id        string
+	UserId    string
+	Name      string
+	TokenHash string
+}
+
+type ApiTokenRevoked struct {
+	EventBase
+	Id     string
+	UserId string
+}
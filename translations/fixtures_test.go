@@ -0,0 +1,77 @@
+package translations
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+// testEventStore is an in memory event store that starts out with only the events a test gives it, no seed project
+type testEventStore struct {
+	*InMemoryEventStore
+	t *testing.T
+}
+
+func newTestEventStore(t *testing.T, events ...Event) *testEventStore {
+	t.Helper()
+	eventStore := &testEventStore{InMemoryEventStore: newInMemoryEventStore(), t: t}
+	err := eventStore.Write(context.Background(), nil, AnyVersion, events...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eventStore
+}
+
+// apply writes the events a command returned, or returns the command's error without writing anything
+func (o *testEventStore) apply(events []Event, err error) error {
+	o.t.Helper()
+	if err != nil {
+		return err
+	}
+	err = o.Write(context.Background(), nil, AnyVersion, events...)
+	if err != nil {
+		o.t.Fatal(err)
+	}
+	return nil
+}
+
+// mustApply is apply for commands that have to succeed
+func (o *testEventStore) mustApply(events []Event, err error) {
+	o.t.Helper()
+	if err := o.apply(events, err); err != nil {
+		o.t.Fatal(err)
+	}
+}
+
+// fixtureProject creates project id in locales, the first is its source locale, with a key per id in valuesByKeyId
+// that has the values by locale, keys are created by id and values in locale order so fixtures always have the same history
+func fixtureProject(id string, locales []string, valuesByKeyId map[string]map[string]string) []Event {
+	ctx := context.Background()
+	events := []Event{
+		ProjectCreated{EventBase: NewEventBase(ctx, id), Id: id, Name: id, Locales: locales},
+	}
+
+	keyIds := []string{}
+	for keyId := range valuesByKeyId {
+		keyIds = append(keyIds, keyId)
+	}
+	sort.Strings(keyIds)
+	for _, keyId := range keyIds {
+		events = append(events, KeyCreated{EventBase: NewEventBase(ctx, id), ProjectId: id, Id: keyId})
+		for _, locale := range locales {
+			value, ok := valuesByKeyId[keyId][locale]
+			if !ok {
+				continue
+			}
+			events = append(events, TranslationUpdated{EventBase: NewEventBase(ctx, id), ProjectId: id, KeyId: keyId, Id: locale, Value: value})
+		}
+	}
+	return events
+}
+
+// greetingProject is p1 in es, its source locale, and en, with a header_1 key that's "Hola" in es and "Hello" in en
+func greetingProject() []Event {
+	return fixtureProject("p1", []string{"es", "en"}, map[string]map[string]string{
+		"header_1": {"es": "Hola", "en": "Hello"},
+	})
+}
@@ -0,0 +1,53 @@
+package translations
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Glossary returns the project's glossary terms, alphabetically
+func (o *Project) Glossary() []*GlossaryTerm {
+	terms := make([]*GlossaryTerm, 0, len(o.GlossaryById))
+	for _, term := range o.GlossaryById {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool { return strings.ToLower(terms[i].Term) < strings.ToLower(terms[j].Term) })
+	return terms
+}
+
+// Target is what term has to be translated to in locale, "" when there's no approved translation
+func (o *GlossaryTerm) Target(locale string) string {
+	if o.DoNotTranslate {
+		return o.Term
+	}
+	return o.Translations[locale]
+}
+
+// containsTerm reports whether value has term as a whole word or words, ignoring case, so "cat" isn't found in "category"
+func containsTerm(value string, term string) bool {
+	value, term = strings.ToLower(value), strings.ToLower(term)
+	if term == "" {
+		return false
+	}
+	for i := 0; i < len(value); {
+		j := strings.Index(value[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		before, _ := utf8.DecodeLastRuneInString(value[:start])
+		after, _ := utf8.DecodeRuneInString(value[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(value[start:])
+		i = start + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
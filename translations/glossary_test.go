@@ -0,0 +1,101 @@
+package translations
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestContainsTerm(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		term     string
+		expected bool
+	}{
+		{"Open the dashboard", "dashboard", true},
+		{"Open the Dashboard.", "dashboard", true},
+		{"Abrir el panel de control", "panel de control", true},
+		{"Dashboards", "dashboard", false},
+		{"Pick a category", "cat", false},
+		{"cat, category", "cat", true},
+		{"Über uns", "über", true},
+		{"", "dashboard", false},
+		{"Open", "", false},
+	} {
+		if actual := containsTerm(test.value, test.term); actual != test.expected {
+			t.Errorf("%q in %q: expected %v, got %v", test.term, test.value, test.expected, actual)
+		}
+	}
+}
+
+func TestGlossary(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+	results := NewQAResults(eventStore, DefaultQARules)
+	warnings := func() []QAWarning {
+		report, err := results.Report(ctx, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		warnings := []QAWarning{}
+		for _, warning := range report.Warnings {
+			if warning.Check == "glossary" {
+				warnings = append(warnings, warning)
+			}
+		}
+		return warnings
+	}
+
+	// p1's source locale is es, header_1 is "Hola" in es and "Hello" in en
+	eventStore.mustApply(AddGlossaryTerm(eventStore)(ctx, AddGlossaryTermInput{ProjectId: "p1", GlossaryEntry: GlossaryEntry{
+		Term:         " hola ",
+		Translations: map[string]string{"en": "Hi", "es": ""},
+	}}))
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	terms := project.Glossary()
+	if len(terms) != 1 || terms[0].Term != "hola" || len(terms[0].Translations) != 1 {
+		t.Fatalf("expected the term trimmed with only its en translation, got %+v", terms)
+	}
+	id := terms[0].Id
+
+	if actual := warnings(); len(actual) != 1 || actual[0].Locale != "en" || actual[0].Message != `"hola" should be translated as "Hi"` {
+		t.Errorf("expected en to be flagged for not using Hi, got %+v", actual)
+	}
+
+	eventStore.mustApply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hi there"}))
+	if actual := warnings(); len(actual) != 0 {
+		t.Errorf("expected no warnings once en uses Hi, got %+v", actual)
+	}
+
+	eventStore.mustApply(UpdateGlossaryTerm(eventStore)(ctx, UpdateGlossaryTermInput{ProjectId: "p1", Id: id, GlossaryEntry: GlossaryEntry{
+		Term:           "Hola",
+		DoNotTranslate: true,
+	}}))
+	if actual := warnings(); len(actual) != 1 || actual[0].Message != `"Hola" isn't translated, keep it as it is` {
+		t.Errorf("expected en to be flagged for translating Hola, got %+v", actual)
+	}
+
+	eventStore.mustApply(RemoveGlossaryTerm(eventStore)(ctx, RemoveGlossaryTermInput{ProjectId: "p1", Id: id}))
+	if actual := warnings(); len(actual) != 0 {
+		t.Errorf("expected no warnings once the term is removed, got %+v", actual)
+	}
+
+	for field, input := range map[string]GlossaryEntry{
+		"Term":         {Term: " "},
+		"Translations": {Term: "adios", DoNotTranslate: true, Translations: map[string]string{"en": "bye"}},
+	} {
+		var validation ValidationError
+		_, err := AddGlossaryTerm(eventStore)(ctx, AddGlossaryTermInput{ProjectId: "p1", GlossaryEntry: input})
+		if !errors.As(err, &validation) || validation.Fields[field] == "" {
+			t.Errorf("expected %+v to be rejected under %s, got %v", input, field, err)
+		}
+	}
+
+	_, err = UpdateGlossaryTerm(eventStore)(ctx, UpdateGlossaryTermInput{ProjectId: "p1", Id: id, GlossaryEntry: GlossaryEntry{Term: "Hola"}})
+	if !errors.Is(err, ErrorNotFound) {
+		t.Errorf("expected a removed term to not be found, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package translations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+Locales
+- identified by BCP 47 language tags (RFC 5646), e.g. "en", "pt-BR", "zh-Hant-TW", "sr-Latn"
+- stored in their canonical casing so "PT-br" and "pt-BR" are the same locale
+*/
+var ErrorInvalidLocale = errors.New("invalid BCP 47 language tag")
+
+// DefaultLocales are what a project starts with when none are given
+var DefaultLocales = []string{"en"}
+
+// legacyLocales are what projects were created with before locales were configurable
+var legacyLocales = []string{"es", "en"}
+
+// CanonicalizeLocale validates tag as a BCP 47 language tag and returns it with canonical casing,
+// underscores are accepted in place of hyphens since that's how a lot of tooling writes them
+func CanonicalizeLocale(tag string) (string, error) {
+	subtags := strings.Split(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	invalid := func(reason string) (string, error) {
+		return "", fmt.Errorf("%w %q: %s", ErrorInvalidLocale, tag, reason)
+	}
+	for _, subtag := range subtags {
+		if subtag == "" || len(subtag) > 8 || !isAlphanumeric(subtag) {
+			return invalid("subtags are 1 to 8 letters or digits")
+		}
+	}
+
+	// language, with up to 3 extended language subtags when it's 2 or 3 letters
+	i := 0
+	language := subtags[i]
+	if !isAlpha(language) || len(language) < 2 {
+		return invalid("language must be 2 to 8 letters")
+	}
+	subtags[i] = strings.ToLower(language)
+	i++
+	if len(language) <= 3 {
+		for extlangs := 0; extlangs < 3 && i < len(subtags) && len(subtags[i]) == 3 && isAlpha(subtags[i]); extlangs++ {
+			subtags[i] = strings.ToLower(subtags[i])
+			i++
+		}
+	}
+
+	// script
+	if i < len(subtags) && len(subtags[i]) == 4 && isAlpha(subtags[i]) {
+		subtags[i] = strings.ToUpper(subtags[i][:1]) + strings.ToLower(subtags[i][1:])
+		i++
+	}
+
+	// region
+	if i < len(subtags) && (len(subtags[i]) == 2 && isAlpha(subtags[i]) || len(subtags[i]) == 3 && isDigits(subtags[i])) {
+		subtags[i] = strings.ToUpper(subtags[i])
+		i++
+	}
+
+	// variants
+	for i < len(subtags) && (len(subtags[i]) >= 5 || len(subtags[i]) == 4 && isDigits(subtags[i][:1])) {
+		subtags[i] = strings.ToLower(subtags[i])
+		i++
+	}
+
+	// extensions and private use, a singleton followed by at least one subtag
+	for i < len(subtags) {
+		singleton := strings.ToLower(subtags[i])
+		if len(singleton) != 1 {
+			return invalid(fmt.Sprintf("unexpected subtag %q", subtags[i]))
+		}
+		subtags[i] = singleton
+		i++
+
+		start := i
+		for i < len(subtags) && (singleton == "x" || len(subtags[i]) >= 2) {
+			subtags[i] = strings.ToLower(subtags[i])
+			i++
+		}
+		if i == start {
+			return invalid(fmt.Sprintf("%q must be followed by a subtag", singleton))
+		}
+	}
+
+	return strings.Join(subtags, "-"), nil
+}
+
+// CanonicalizeLocales canonicalizes every tag and drops duplicates, keeping the first occurrence's position
+func CanonicalizeLocales(tags []string) ([]string, error) {
+	locales := []string{}
+	for _, tag := range tags {
+		locale, err := CanonicalizeLocale(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !Contains(locales, locale) {
+			locales = append(locales, locale)
+		}
+	}
+	return locales, nil
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package translations
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonicalizeLocale(t *testing.T) {
+	for tag, expected := range map[string]string{
+		"en":                 "en",
+		"pt_br":              "pt-BR",
+		"ZH-hant-tw":         "zh-Hant-TW",
+		"es-419":             "es-419",
+		"sl-rozaj-biske":     "sl-rozaj-biske",
+		"de-CH-1901":         "de-CH-1901",
+		"zh-yue-HK":          "zh-yue-HK",
+		"en-US-u-ca-gregory": "en-US-u-ca-gregory",
+		"en-x-Pirate":        "en-x-pirate",
+	} {
+		locale, err := CanonicalizeLocale(tag)
+		if err != nil {
+			t.Errorf("%s: %v", tag, err)
+		}
+		if locale != expected {
+			t.Errorf("%s: expected %s, got %s", tag, expected, locale)
+		}
+	}
+
+	for _, tag := range []string{"", "e", "en-", "123", "en-$", "en-US-u", "en-a-b"} {
+		_, err := CanonicalizeLocale(tag)
+		if !errors.Is(err, ErrorInvalidLocale) {
+			t.Errorf("%q: expected ErrorInvalidLocale, got %v", tag, err)
+		}
+	}
+}
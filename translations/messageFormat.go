@@ -0,0 +1,377 @@
+package translations
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+MessageFormat
+- a translation value parsed as ICU MessageFormat, text and {arguments} in order
+- quoting follows ICU: ” is an apostrophe, a ' before {, } (or # in a plural) quotes up to the next ', any other ' is just an apostrophe
+- # is the number of the closest plural, outside of one it's just text
+*/
+type MessageFormat []MessagePart
+
+// MessagePart is one of Text, Pound or Argument
+type MessagePart struct {
+	Text     string
+	Pound    bool
+	Argument *MessageArgument
+}
+
+type MessageArgument struct {
+	Name  string
+	Type  string // empty for {name}, otherwise e.g. number, plural or select
+	Style string // what comes after the type of simple arguments, e.g. integer in {n, number, integer}
+
+	// plural, selectordinal and select
+	Offset  int
+	Options []MessageOption
+}
+
+type MessageOption struct {
+	Selector string // =N or a plural category for plurals, anything for select
+	Message  MessageFormat
+}
+
+// MessageFormatError is where and why a value stopped parsing, Offset is in bytes, Line and Column in characters from 1
+type MessageFormatError struct {
+	Value   string
+	Offset  int
+	Line    int
+	Column  int
+	Message string
+}
+
+func (o MessageFormatError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", o.Line, o.Column, o.Message)
+}
+
+// simpleArgumentTypes can be followed by a style, which isn't checked
+var simpleArgumentTypes = []string{"number", "date", "time", "spellout", "ordinal", "duration"}
+
+// ParseMessageFormat parses value, the error is a MessageFormatError when it isn't valid
+func ParseMessageFormat(value string) (MessageFormat, error) {
+	p := &messageFormatParser{value: value}
+	message, err := p.message(0, false)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// CheckMessageFormat returns where value stops being valid, nil when it's valid,
+// values with {{name}} placeholders are in a different syntax and aren't checked
+func CheckMessageFormat(value string) *MessageFormatError {
+	if strings.Contains(value, "{{") {
+		return nil
+	}
+	_, err := ParseMessageFormat(value)
+	var parseErr MessageFormatError
+	if !errors.As(err, &parseErr) {
+		return nil
+	}
+	return &parseErr
+}
+
+type messageFormatParser struct {
+	value string
+	i     int
+}
+
+func (p *messageFormatParser) fail(offset int, format string, args ...any) error {
+	before := p.value[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return MessageFormatError{
+		Value:   p.value,
+		Offset:  offset,
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// position describes offset the way errors do, for pointing back at an earlier part of the value
+func (p *messageFormatParser) position(offset int) string {
+	err := p.fail(offset, "").(MessageFormatError)
+	return fmt.Sprintf("line %d, column %d", err.Line, err.Column)
+}
+
+func (p *messageFormatParser) done() bool {
+	return p.i >= len(p.value)
+}
+
+func (p *messageFormatParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.value[p.i]
+}
+
+func (p *messageFormatParser) skipSpace() {
+	for !p.done() {
+		r, size := utf8.DecodeRuneInString(p.value[p.i:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.i += size
+	}
+}
+
+// identifier reads letters, digits and underscores, which is what argument names, types and selectors are made of
+func (p *messageFormatParser) identifier() string {
+	start := p.i
+	for !p.done() {
+		r, size := utf8.DecodeRuneInString(p.value[p.i:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		p.i += size
+	}
+	return p.value[start:p.i]
+}
+
+// message reads text and arguments up to the } that closes it, or the end of the value at the top level
+func (p *messageFormatParser) message(depth int, inPlural bool) (MessageFormat, error) {
+	message := MessageFormat{}
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			message = append(message, MessagePart{Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for !p.done() {
+		c := p.value[p.i]
+		switch {
+		case c == '\'':
+			p.quoted(&text, inPlural)
+		case c == '{':
+			flush()
+			argument, err := p.argument(depth, inPlural)
+			if err != nil {
+				return nil, err
+			}
+			message = append(message, MessagePart{Argument: argument})
+		case c == '}':
+			if depth == 0 {
+				return nil, p.fail(p.i, "} doesn't close anything, quote it as '}' to show it")
+			}
+			flush()
+			return message, nil
+		case c == '#' && inPlural:
+			flush()
+			message = append(message, MessagePart{Pound: true})
+			p.i++
+		default:
+			text.WriteByte(c)
+			p.i++
+		}
+	}
+	flush()
+	return message, nil
+}
+
+// quoted reads an apostrophe and whatever it quotes into text
+func (p *messageFormatParser) quoted(text *strings.Builder, inPlural bool) {
+	p.i++
+	switch next := p.peek(); {
+	case next == '\'':
+		text.WriteByte('\'')
+		p.i++
+		return
+	case next == '{' || next == '}' || (next == '#' && inPlural):
+	default:
+		text.WriteByte('\'')
+		return
+	}
+
+	// an unclosed quote runs to the end of the value
+	for !p.done() {
+		c := p.value[p.i]
+		p.i++
+		if c != '\'' {
+			text.WriteByte(c)
+			continue
+		}
+		if p.peek() != '\'' {
+			return
+		}
+		text.WriteByte('\'')
+		p.i++
+	}
+}
+
+func (p *messageFormatParser) argument(depth int, inPlural bool) (*MessageArgument, error) {
+	start := p.i
+	p.i++
+	p.skipSpace()
+	argument := &MessageArgument{Name: p.identifier()}
+	if argument.Name == "" {
+		return nil, p.unexpected(start, "an argument name")
+	}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.i++
+		return argument, nil
+	}
+	if p.peek() != ',' {
+		return nil, p.unexpected(start, ", or } after the argument name")
+	}
+	p.i++
+	p.skipSpace()
+
+	typeStart := p.i
+	argument.Type = p.identifier()
+	if argument.Type == "" {
+		return nil, p.unexpected(start, "an argument type")
+	}
+	p.skipSpace()
+
+	switch {
+	case argument.Type == "plural" || argument.Type == "selectordinal" || argument.Type == "select":
+		if p.peek() != ',' {
+			return nil, p.unexpected(start, fmt.Sprintf(", and the options of the %s", argument.Type))
+		}
+		p.i++
+		err := p.options(argument, start, depth, inPlural)
+		if err != nil {
+			return nil, err
+		}
+		return argument, nil
+	case Contains(simpleArgumentTypes, argument.Type):
+		if p.peek() == ',' {
+			p.i++
+			style, err := p.style(start)
+			if err != nil {
+				return nil, err
+			}
+			argument.Style = style
+		}
+		if p.peek() != '}' {
+			return nil, p.unexpected(start, "} after the argument type")
+		}
+		p.i++
+		return argument, nil
+	default:
+		return nil, p.fail(typeStart, "%s isn't an argument type, use one of %s, plural, selectordinal or select", argument.Type, strings.Join(simpleArgumentTypes, ", "))
+	}
+}
+
+// unexpected fails at the current position, which is the end of the value when the { at start is never closed
+func (p *messageFormatParser) unexpected(start int, expected string) error {
+	if p.done() {
+		return p.fail(p.i, "the { at %s is never closed", p.position(start))
+	}
+	return p.fail(p.i, "expected %s", expected)
+}
+
+// style reads everything up to the } that closes the argument, skipping over quotes and nested braces
+func (p *messageFormatParser) style(start int) (string, error) {
+	styleStart := p.i
+	depth := 0
+	for !p.done() {
+		switch p.value[p.i] {
+		case '\'':
+			var ignored strings.Builder
+			p.quoted(&ignored, false)
+			continue
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				style := strings.TrimSpace(p.value[styleStart:p.i])
+				if style == "" {
+					return "", p.fail(p.i, "expected a style after the ,")
+				}
+				return style, nil
+			}
+			depth--
+		}
+		p.i++
+	}
+	return "", p.unexpected(start, "")
+}
+
+// options reads the selectors and messages of a plural, selectordinal or select and the } that closes it,
+// # stays the plural's number in a select inside of one
+func (p *messageFormatParser) options(argument *MessageArgument, start int, depth int, inPlural bool) error {
+	plural := argument.Type != "select"
+	selectors := map[string]bool{}
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			break
+		}
+
+		selectorStart := p.i
+		if plural && len(argument.Options) == 0 && strings.HasPrefix(p.value[p.i:], "offset:") {
+			p.i += len("offset:")
+			p.skipSpace()
+			digitsStart := p.i
+			for p.peek() >= '0' && p.peek() <= '9' {
+				p.i++
+			}
+			offset, err := strconv.Atoi(p.value[digitsStart:p.i])
+			if err != nil {
+				return p.unexpected(start, "a number after offset:")
+			}
+			argument.Offset = offset
+			continue
+		}
+
+		var selector string
+		if plural && p.peek() == '=' {
+			p.i++
+			digitsStart := p.i
+			for p.peek() >= '0' && p.peek() <= '9' {
+				p.i++
+			}
+			if p.i == digitsStart {
+				return p.unexpected(start, "a number after =")
+			}
+			selector = p.value[selectorStart:p.i]
+		} else {
+			selector = p.identifier()
+			if selector == "" {
+				return p.unexpected(start, "a selector or } to close the "+argument.Type)
+			}
+			if plural && !Contains(PluralCategoriesInOrder, PluralCategory(selector)) {
+				return p.fail(selectorStart, "%s isn't a plural category, use zero, one, two, few, many, other or =number", selector)
+			}
+		}
+		if selectors[selector] {
+			return p.fail(selectorStart, "%s is already an option", selector)
+		}
+		selectors[selector] = true
+
+		p.skipSpace()
+		messageStart := p.i
+		if p.peek() != '{' {
+			return p.unexpected(start, fmt.Sprintf("{ to start the %s message", selector))
+		}
+		p.i++
+		message, err := p.message(depth+1, plural || inPlural)
+		if err != nil {
+			return err
+		}
+		if p.done() {
+			return p.fail(p.i, "the { at %s is never closed", p.position(messageStart))
+		}
+		p.i++
+		argument.Options = append(argument.Options, MessageOption{Selector: selector, Message: message})
+	}
+
+	if !selectors["other"] {
+		return p.fail(p.i, "the %s needs an other option", argument.Type)
+	}
+	p.i++
+	return nil
+}
@@ -0,0 +1,94 @@
+package translations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseMessageFormat(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"Hello",
+		"Hello {name}",
+		"It's {name}'s",
+		"'{name}' is not an argument, '' is an apostrophe",
+		"{ count , number , integer }",
+		"{price, number, ::currency/EUR}",
+		"{count, plural, =0 {no items} one {# item} other {# items}}",
+		"{count, plural, offset:1 =0 {nobody} =1 {{name}} other {{name} and # others}}",
+		"{place, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}",
+		"{gender, select, female {{count, plural, one {her item} other {her # items}}} other {{count, plural, one {their item} other {their # items}}}}",
+		"# is just text outside of a plural",
+		"{count, plural, other {'#' isn't the count}}",
+		"line one\n{name}",
+	} {
+		_, err := ParseMessageFormat(value)
+		if err != nil {
+			t.Errorf("%q: %v", value, err)
+		}
+	}
+
+	for value, expected := range map[string]MessageFormatError{
+		"Hello {":                              {Line: 1, Column: 8, Offset: 7},
+		"Hello {name":                          {Line: 1, Column: 12, Offset: 11},
+		"Hello name}":                          {Line: 1, Column: 11, Offset: 10},
+		"Hello {first name}":                   {Line: 1, Column: 14, Offset: 13},
+		"{count, plurl, one {#} other {#}}":    {Line: 1, Column: 9, Offset: 8},
+		"{count, plural, one {#}}":             {Line: 1, Column: 24, Offset: 23},
+		"{count, plural, one {#} othr {#}}":    {Line: 1, Column: 25, Offset: 24},
+		"{count, plural, one {#} one {#}}":     {Line: 1, Column: 25, Offset: 24},
+		"{count, plural, one # other {#}}":     {Line: 1, Column: 21, Offset: 20},
+		"{count, plural, one {#} other {#}":    {Line: 1, Column: 34, Offset: 33},
+		"{gender, select, male {him}}":         {Line: 1, Column: 28, Offset: 27},
+		"{n, number, }":                        {Line: 1, Column: 13, Offset: 12},
+		"¡Hola {nombre!":                       {Line: 1, Column: 14, Offset: 14},
+		"first line\n{count, plural, one {#}}": {Line: 2, Column: 24, Offset: 34},
+	} {
+		_, err := ParseMessageFormat(value)
+		var parseErr MessageFormatError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: expected a MessageFormatError, got %v", value, err)
+			continue
+		}
+		if parseErr.Line != expected.Line || parseErr.Column != expected.Column || parseErr.Offset != expected.Offset {
+			t.Errorf("%q: expected line %d, column %d, offset %d, got %+v", value, expected.Line, expected.Column, expected.Offset, parseErr)
+		}
+	}
+}
+
+func TestParseMessageFormatParts(t *testing.T) {
+	message, err := ParseMessageFormat("You have {count, plural, one {# item} other {# items}}, '{name}'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := MessageFormat{
+		{Text: "You have "},
+		{Argument: &MessageArgument{Name: "count", Type: "plural", Options: []MessageOption{
+			{Selector: "one", Message: MessageFormat{{Pound: true}, {Text: " item"}}},
+			{Selector: "other", Message: MessageFormat{{Pound: true}, {Text: " items"}}},
+		}}},
+		{Text: ", {name}"},
+	}
+	if !reflect.DeepEqual(message, expected) {
+		t.Errorf("expected %+v, got %+v", expected, message)
+	}
+}
+
+func TestUpdateTranslationMessageFormat(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+
+	var validation ValidationError
+	_, err := UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello {name"})
+	if !errors.As(err, &validation) || validation.Fields["Value"] == "" {
+		t.Errorf("expected an invalid value to be rejected, got %v", err)
+	}
+
+	// {{name}} placeholders aren't MessageFormat, so they're left alone
+	_, err = UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello {{name}"})
+	if err != nil {
+		t.Errorf("expected a {{name}} value to not be checked, got %v", err)
+	}
+}
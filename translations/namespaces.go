@@ -0,0 +1,124 @@
+package translations
+
+import (
+	"sort"
+	"strings"
+)
+
+// NamespaceSeparator splits key ids into namespaces, checkout.payment.error.declined is the key declined in checkout.payment.error
+const NamespaceSeparator = "."
+
+/*
+Namespace
+- the keys whose ids start with Path, as a tree
+- nothing is stored, namespaces are worked out from the key ids whenever they're needed
+*/
+type Namespace struct {
+	Project *Project `json:"-"`
+	Path    string   // "" for the whole project
+	Name    string   // last part of Path
+	// only keys with a translation in one of these are included, all keys when it's empty
+	Statuses []TranslationStatus
+
+	Keys       []*Key       // directly in this namespace, by id
+	Namespaces []*Namespace // by name
+
+	// counted over the whole subtree
+	KeyCount     int
+	MissingCount int // translations without a value
+}
+
+// KeyNamespace is the namespace a key id is directly in, "" for keys without a separator
+func KeyNamespace(keyId string) string {
+	i := strings.LastIndex(keyId, NamespaceSeparator)
+	if i < 0 {
+		return ""
+	}
+	return keyId[:i]
+}
+
+// Namespace returns the tree of keys under path, "" for all of them, that have a translation in one of statuses if any are given
+func (o *Project) Namespace(path string, statuses ...TranslationStatus) *Namespace {
+	path = strings.Trim(path, NamespaceSeparator)
+	root := &Namespace{
+		Project:  o,
+		Path:     path,
+		Name:     path[strings.LastIndex(path, NamespaceSeparator)+1:],
+		Statuses: statuses,
+	}
+
+	for _, key := range o.KeysById {
+		relative := key.Id
+		if path != "" {
+			var ok bool
+			relative, ok = strings.CutPrefix(key.Id, path+NamespaceSeparator)
+			if !ok {
+				continue
+			}
+		}
+		if len(statuses) > 0 && !key.HasStatus(statuses...) {
+			continue
+		}
+
+		missing := len(key.Missing())
+		namespace := root
+		namespace.KeyCount++
+		namespace.MissingCount += missing
+		parts := strings.Split(relative, NamespaceSeparator)
+		for _, name := range parts[:len(parts)-1] {
+			namespace = namespace.child(name)
+			namespace.KeyCount++
+			namespace.MissingCount += missing
+		}
+		namespace.Keys = append(namespace.Keys, key)
+	}
+
+	root.sort()
+	return root
+}
+
+func (o *Namespace) child(name string) *Namespace {
+	for _, namespace := range o.Namespaces {
+		if namespace.Name == name {
+			return namespace
+		}
+	}
+
+	path := name
+	if o.Path != "" {
+		path = o.Path + NamespaceSeparator + name
+	}
+	namespace := &Namespace{Project: o.Project, Path: path, Name: name}
+	o.Namespaces = append(o.Namespaces, namespace)
+	return namespace
+}
+
+func (o *Namespace) sort() {
+	sort.Slice(o.Keys, func(i, j int) bool { return o.Keys[i].Id < o.Keys[j].Id })
+	sort.Slice(o.Namespaces, func(i, j int) bool { return o.Namespaces[i].Name < o.Namespaces[j].Name })
+	for _, namespace := range o.Namespaces {
+		namespace.sort()
+	}
+}
+
+// HasStatus reports whether any of the key's translations is in one of statuses
+func (o *Key) HasStatus(statuses ...TranslationStatus) bool {
+	for _, translation := range o.TranslationsById {
+		if Contains(statuses, translation.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the locales the key has no value in, sorted
+func (o *Key) Missing() []string {
+	missing := []string{}
+	for locale, translation := range o.TranslationsById {
+		if translation.IsMissing() {
+			missing = append(missing, locale)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,58 @@
+package translations
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProjectNamespace(t *testing.T) {
+	ctx := context.Background()
+	var project Project
+	project.Reduce(ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Locales: []string{"en", "es"}})
+	for _, id := range []string{"title", "checkout.title", "checkout.payment.error.declined", "checkout.payment.error.expired", "checkout.payment.submit"} {
+		project.Reduce(KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: id})
+		project.Reduce(TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: id, Id: "en", Value: id})
+	}
+
+	root := project.Namespace("")
+	if root.KeyCount != 5 || root.MissingCount != 5 {
+		t.Errorf("expected 5 keys missing 5 translations, got %d and %d", root.KeyCount, root.MissingCount)
+	}
+	if len(root.Keys) != 1 || root.Keys[0].Id != "title" || len(root.Namespaces) != 1 || root.Namespaces[0].Path != "checkout" {
+		t.Errorf("expected the title key and the checkout namespace at the root, got %+v", root)
+	}
+
+	payment := project.Namespace("checkout.payment")
+	if payment.Name != "payment" || payment.KeyCount != 3 || len(payment.Keys) != 1 {
+		t.Errorf("expected 3 keys under checkout.payment with 1 directly in it, got %+v", payment)
+	}
+	if errors := payment.Namespaces[0]; errors.Path != "checkout.payment.error" || errors.Keys[0].Id != "checkout.payment.error.declined" {
+		t.Errorf("expected declined first in checkout.payment.error, got %+v", errors)
+	}
+
+	if namespace := project.Namespace("check"); namespace.KeyCount != 0 {
+		t.Errorf("expected namespaces to only match whole parts, got %d keys", namespace.KeyCount)
+	}
+	if KeyNamespace("checkout.payment.submit") != "checkout.payment" || KeyNamespace("title") != "" {
+		t.Error("expected KeyNamespace to drop the last part")
+	}
+}
+
+func TestProjectNamespaceStatuses(t *testing.T) {
+	ctx := context.Background()
+	var project Project
+	project.Reduce(ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Locales: []string{"en", "es"}})
+	for _, id := range []string{"home.title", "home.subtitle", "checkout.title"} {
+		project.Reduce(KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: id})
+		project.Reduce(TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: id, Id: "en", Value: id})
+	}
+	project.Reduce(TranslationSubmitted{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: "home.title", Id: "en"})
+
+	namespace := project.Namespace("", StatusNeedsReview)
+	if namespace.KeyCount != 1 || namespace.Namespaces[0].Keys[0].Id != "home.title" {
+		t.Errorf("expected only home.title to need review, got %+v", namespace)
+	}
+	if namespace := project.Namespace("home", StatusDraft, StatusApproved); namespace.KeyCount != 1 || namespace.Keys[0].Id != "home.subtitle" {
+		t.Errorf("expected only home.subtitle to be a draft in home, got %+v", namespace)
+	}
+}
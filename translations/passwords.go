@@ -0,0 +1,77 @@
+package translations
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+/*
+Passwords
+- hashed with PBKDF2-HMAC-SHA256, stored as pbkdf2-sha256$<iterations>$<salt>$<key> so the cost can be raised later
+- API tokens are random enough that a plain SHA-256 is all they need, which also lets them be looked up by hash
+*/
+const (
+	passwordHashIterations = 600_000
+	passwordSaltLength     = 16
+	passwordKeyLength      = 32
+	apiTokenLength         = 32
+	apiTokenPrefix         = "tdb_"
+)
+
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := pbkdf2.Key([]byte(password), salt, passwordHashIterations, passwordKeyLength, sha256.New)
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s",
+		passwordHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func CheckPassword(passwordHash string, password string) bool {
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(key, pbkdf2.Key([]byte(password), salt, iterations, len(key), sha256.New)) == 1
+}
+
+// NewApiToken returns a new random token, it's only ever shown to its owner once
+func NewApiToken() (string, error) {
+	token := make([]byte, apiTokenLength)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+func HashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return base64.RawStdEncoding.EncodeToString(hash[:])
+}
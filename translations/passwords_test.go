@@ -0,0 +1,36 @@
+package translations
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestCheckPasswordFormat(t *testing.T) {
+	// PBKDF2-HMAC-SHA256 test vector from RFC 7914 section 11, in the format hashes have always been stored in
+	key, _ := hex.DecodeString("4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56")
+	passwordHash := fmt.Sprintf("pbkdf2-sha256$80000$%s$%s",
+		base64.RawStdEncoding.EncodeToString([]byte("NaCl")),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+	if !CheckPassword(passwordHash, "Password") {
+		t.Errorf("expected %s to match", passwordHash)
+	}
+	if CheckPassword(passwordHash, "password") {
+		t.Error("expected the wrong password not to match")
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	passwordHash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPassword(passwordHash, "correct horse") {
+		t.Error("expected the right password to match")
+	}
+	if CheckPassword(passwordHash, "battery staple") {
+		t.Error("expected the wrong password not to match")
+	}
+}
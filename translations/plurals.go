@@ -0,0 +1,86 @@
+package translations
+
+import "strings"
+
+// PluralCategory is a CLDR plural category, which of them a language uses and for which numbers is up to the language
+type PluralCategory string
+
+const (
+	PluralZero  PluralCategory = "zero"
+	PluralOne   PluralCategory = "one"
+	PluralTwo   PluralCategory = "two"
+	PluralFew   PluralCategory = "few"
+	PluralMany  PluralCategory = "many"
+	PluralOther PluralCategory = "other"
+)
+
+// PluralCategoriesInOrder are all the categories, in the order CLDR lists them
+var PluralCategoriesInOrder = []PluralCategory{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther}
+
+// pluralCategoriesByLanguage are the cardinal categories of languages that don't just use one and other, from CLDR 44
+var pluralCategoriesByLanguage = map[string][]PluralCategory{
+	// no plural forms
+	"ja": {PluralOther}, "zh": {PluralOther}, "ko": {PluralOther}, "vi": {PluralOther}, "th": {PluralOther},
+	"id": {PluralOther}, "ms": {PluralOther}, "lo": {PluralOther}, "my": {PluralOther}, "km": {PluralOther},
+	// 1 million is "de millones"
+	"es": {PluralOne, PluralMany, PluralOther}, "fr": {PluralOne, PluralMany, PluralOther},
+	"it": {PluralOne, PluralMany, PluralOther}, "pt": {PluralOne, PluralMany, PluralOther},
+	"ca": {PluralOne, PluralMany, PluralOther},
+	// slavic and baltic
+	"ru": {PluralOne, PluralFew, PluralMany, PluralOther}, "uk": {PluralOne, PluralFew, PluralMany, PluralOther},
+	"be": {PluralOne, PluralFew, PluralMany, PluralOther}, "pl": {PluralOne, PluralFew, PluralMany, PluralOther},
+	"cs": {PluralOne, PluralFew, PluralMany, PluralOther}, "sk": {PluralOne, PluralFew, PluralMany, PluralOther},
+	"lt": {PluralOne, PluralFew, PluralMany, PluralOther},
+	"hr": {PluralOne, PluralFew, PluralOther}, "sr": {PluralOne, PluralFew, PluralOther}, "bs": {PluralOne, PluralFew, PluralOther},
+	"ro": {PluralOne, PluralFew, PluralOther},
+	"sl": {PluralOne, PluralTwo, PluralFew, PluralOther},
+	"lv": {PluralZero, PluralOne, PluralOther},
+	"he": {PluralOne, PluralTwo, PluralOther},
+	"ga": {PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+	"mt": {PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+	"ar": {PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+	"cy": {PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+}
+
+// PluralCategories returns the categories a plural value in locale needs, in CLDR order
+func PluralCategories(locale string) []PluralCategory {
+	language := strings.ToLower(strings.SplitN(locale, "-", 2)[0])
+	if categories, ok := pluralCategoriesByLanguage[language]; ok {
+		return categories
+	}
+	return []PluralCategory{PluralOne, PluralOther}
+}
+
+// Categories returns the plural categories the translation needs, nil when its key isn't plural
+func (o *Translation) Categories() []PluralCategory {
+	if !o.Plural {
+		return nil
+	}
+	return PluralCategories(o.Id)
+}
+
+// hasValue reports whether the translation has a value, or a value for any plural category
+func (o *Translation) hasValue() bool {
+	if o.Value != "" {
+		return true
+	}
+	for _, value := range o.Plurals {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMissing reports whether the translation has no value, or is plural and lacks one of the categories its locale needs
+func (o *Translation) IsMissing() bool {
+	if !o.Plural {
+		return o.Value == ""
+	}
+	for _, category := range o.Categories() {
+		if o.Plurals[category] == "" {
+			return true
+		}
+	}
+	return false
+}
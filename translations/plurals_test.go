@@ -0,0 +1,67 @@
+package translations
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPluralCategories(t *testing.T) {
+	for locale, expected := range map[string][]PluralCategory{
+		"en":    {PluralOne, PluralOther},
+		"en-GB": {PluralOne, PluralOther},
+		"ja":    {PluralOther},
+		"ru":    {PluralOne, PluralFew, PluralMany, PluralOther},
+		"ar-EG": {PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+	} {
+		if actual := PluralCategories(locale); !slices.Equal(actual, expected) {
+			t.Errorf("expected %s to have categories %v, got %v", locale, expected, actual)
+		}
+	}
+}
+
+func TestPluralTranslations(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+	translation := func(locale string) *Translation {
+		project, err := GetProject(ctx, eventStore, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return project.KeysById["header_1"].TranslationsById[locale]
+	}
+
+	var validation ValidationError
+	err := eventStore.apply(UpdatePluralTranslation(eventStore)(ctx, UpdatePluralTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Plurals: map[PluralCategory]string{PluralOne: "Hello"}}))
+	if !errors.As(err, &validation) || validation.Fields["KeyId"] == "" {
+		t.Errorf("expected plural values to need a plural key, got %v", err)
+	}
+
+	eventStore.mustApply(SetKeyPlural(eventStore)(ctx, SetKeyPluralInput{ProjectId: "p1", Id: "header_1", Plural: true}))
+	if other := translation("en").Plurals[PluralOther]; other != "Hello" {
+		t.Errorf("expected the value to become the other category, got %q", other)
+	}
+	if !translation("en").IsMissing() {
+		t.Error("expected en to be missing its one category")
+	}
+
+	err = eventStore.apply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hi"}))
+	if !errors.As(err, &validation) || validation.Fields["Value"] == "" {
+		t.Errorf("expected a single value to be rejected for a plural key, got %v", err)
+	}
+	err = eventStore.apply(UpdatePluralTranslation(eventStore)(ctx, UpdatePluralTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Plurals: map[PluralCategory]string{PluralFew: "Hellos"}}))
+	if !errors.As(err, &validation) || validation.Fields["Plurals"] == "" {
+		t.Errorf("expected en to not allow the few category, got %v", err)
+	}
+
+	eventStore.mustApply(UpdatePluralTranslation(eventStore)(ctx, UpdatePluralTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Plurals: map[PluralCategory]string{PluralOne: "Hello", PluralOther: "Hellos"}}))
+	if translation("en").IsMissing() || translation("en").Value != "Hellos" {
+		t.Errorf("expected en to have every category and other as its value, got %+v", translation("en"))
+	}
+
+	eventStore.mustApply(SetKeyPlural(eventStore)(ctx, SetKeyPluralInput{ProjectId: "p1", Id: "header_1", Plural: false}))
+	if en := translation("en"); en.Plurals != nil || en.Value != "Hellos" {
+		t.Errorf("expected en to be back to the other category as a single value, got %+v", en)
+	}
+}
@@ -0,0 +1,209 @@
+package translations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+QA
+- rules check translations for problems that don't stop them from being saved, reported as warnings
+- every rule in DefaultQARules runs unless a project disables it, see QARuleDisabled
+- QAResults keeps the warnings of every project up to date as translations change
+*/
+
+// QAWarning is a problem with the translation of KeyId in Locale
+type QAWarning struct {
+	KeyId   string
+	Locale  string
+	Check   string // the name of the rule that found it
+	Message string
+
+	// for the placeholders rule, what the translation lacks and has on top of the source
+	Missing []string `json:",omitempty"`
+	Extra   []string `json:",omitempty"`
+}
+
+// QAReport is every warning in a project, ordered by key then by the project's locale order
+type QAReport struct {
+	ProjectId string
+	Warnings  []QAWarning
+}
+
+/*
+QARule
+- Name is how projects enable and disable the rule, it's stored in events so it must never change
+- Check returns nil when translation is fine, otherwise a warning with at least a Message, who it's about is filled in for it
+*/
+type QARule interface {
+	Name() string
+	Description() string
+	Check(project *Project, key *Key, translation *Translation) *QAWarning
+}
+
+var ErrorUnknownQARule = errors.New("no QA rule registered under name")
+
+// QARules is a registry of QA rules by name
+type QARules struct {
+	mu          sync.RWMutex
+	rulesByName map[string]QARule
+}
+
+var DefaultQARules = newDefaultQARules()
+
+func newDefaultQARules() *QARules {
+	rules := NewQARules()
+	for _, rule := range []QARule{
+		placeholdersRule{},
+		whitespaceRule{},
+		doubleSpacesRule{},
+		punctuationRule{},
+		identicalToSourceRule{},
+		htmlTagsRule{},
+		maxLengthRule{},
+		glossaryRule{},
+	} {
+		rules.MustRegister(rule)
+	}
+	return rules
+}
+
+func NewQARules() *QARules {
+	return &QARules{
+		rulesByName: map[string]QARule{},
+	}
+}
+
+func (o *QARules) Register(rule QARule) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.rulesByName[rule.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrorDuplicateName, rule.Name())
+	}
+	o.rulesByName[rule.Name()] = rule
+	return nil
+}
+
+func (o *QARules) MustRegister(rule QARule) {
+	err := o.Register(rule)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (o *QARules) Get(name string) (QARule, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	rule, ok := o.rulesByName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownQARule, name)
+	}
+	return rule, nil
+}
+
+// All returns every registered rule, by name
+func (o *QARules) All() []QARule {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	rules := make([]QARule, 0, len(o.rulesByName))
+	for _, rule := range o.rulesByName {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name() < rules[j].Name() })
+	return rules
+}
+
+// QARuleEnabled reports whether the project runs the rule, rules are enabled until they're disabled
+func (o *Project) QARuleEnabled(name string) bool {
+	return !Contains(o.DisabledQARules, name)
+}
+
+// CheckKey runs the rules the project has enabled on every translation of key, in the project's locale order
+func (o *Project) CheckKey(rules *QARules, key *Key) []QAWarning {
+	warnings := []QAWarning{}
+	for _, locale := range o.Locales {
+		translation, ok := key.TranslationsById[locale]
+		if !ok {
+			continue
+		}
+		for _, rule := range rules.All() {
+			if !o.QARuleEnabled(rule.Name()) {
+				continue
+			}
+			warning := rule.Check(o, key, translation)
+			if warning == nil {
+				continue
+			}
+			warning.KeyId = key.Id
+			warning.Locale = locale
+			warning.Check = rule.Name()
+			warnings = append(warnings, *warning)
+		}
+	}
+	return warnings
+}
+
+// qaValue is one value of a translation, with the source locale's value it was translated from when there is one
+type qaValue struct {
+	Category PluralCategory // "" unless the key is plural
+	Value    string
+	Source   string
+}
+
+// label tells plural values apart in warnings
+func (o qaValue) label(message string) string {
+	if o.Category == "" {
+		return message
+	}
+	return fmt.Sprintf("%s: %s", o.Category, message)
+}
+
+// qaValues returns the non empty values of translation, a value per plural category for plural keys,
+// a plural category the source doesn't have is compared with the source's other
+func qaValues(project *Project, key *Key, translation *Translation) []qaValue {
+	source := &Translation{}
+	if translation.Id != project.SourceLocale && key.TranslationsById[project.SourceLocale] != nil {
+		source = key.TranslationsById[project.SourceLocale]
+	}
+
+	if !translation.Plural {
+		if translation.Value == "" {
+			return nil
+		}
+		return []qaValue{{Value: translation.Value, Source: source.Value}}
+	}
+
+	values := []qaValue{}
+	for _, category := range PluralCategoriesInOrder {
+		value := translation.Plurals[category]
+		if value == "" {
+			continue
+		}
+		sourceValue, ok := source.Plurals[category]
+		if !ok {
+			sourceValue = source.Plurals[PluralOther]
+		}
+		values = append(values, qaValue{Category: category, Value: value, Source: sourceValue})
+	}
+	return values
+}
+
+// checkValues runs check on every value of translation, the warning has every problem found
+func checkValues(project *Project, key *Key, translation *Translation, check func(value qaValue) string) *QAWarning {
+	problems := []string{}
+	for _, value := range qaValues(project, key, translation) {
+		if problem := check(value); problem != "" {
+			problems = append(problems, value.label(problem))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &QAWarning{Message: strings.Join(problems, "; ")}
+}
@@ -0,0 +1,348 @@
+package translations
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the rules in DefaultQARules, the ones about differences compare with the source locale's value of the same key
+
+type placeholdersRule struct{}
+
+func (placeholdersRule) Name() string { return "placeholders" }
+
+func (placeholdersRule) Description() string {
+	return "Translations keep the %s, {{name}} and {name} placeholders of the source"
+}
+
+// Check compares every placeholder a translation uses in any of its values, a plural category doesn't need all of them
+func (placeholdersRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	source, ok := key.TranslationsById[project.SourceLocale]
+	if !ok || translation.Id == project.SourceLocale || !source.hasValue() || !translation.hasValue() {
+		return nil
+	}
+
+	expected := translationPlaceholders(source)
+	actual := translationPlaceholders(translation)
+	missing := placeholdersNotIn(expected, actual)
+	extra := placeholdersNotIn(actual, expected)
+	if len(missing) == 0 && len(extra) == 0 {
+		return nil
+	}
+
+	problems := []string{}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing %s", strings.Join(missing, ", ")))
+	}
+	if len(extra) > 0 {
+		problems = append(problems, fmt.Sprintf("has %s, which the %s source doesn't", strings.Join(extra, ", "), project.SourceLocale))
+	}
+	return &QAWarning{
+		Message: strings.Join(problems, "; "),
+		Missing: missing,
+		Extra:   extra,
+	}
+}
+
+type whitespaceRule struct{}
+
+func (whitespaceRule) Name() string { return "whitespace" }
+
+func (whitespaceRule) Description() string {
+	return "Translations start and end with the same whitespace as the source"
+}
+
+func (whitespaceRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	return checkValues(project, key, translation, func(value qaValue) string {
+		if value.Source == "" {
+			return ""
+		}
+		problems := []string{}
+		if leadingSpace(value.Value) != leadingSpace(value.Source) {
+			problems = append(problems, "leading whitespace differs from the source")
+		}
+		if trailingSpace(value.Value) != trailingSpace(value.Source) {
+			problems = append(problems, "trailing whitespace differs from the source")
+		}
+		return strings.Join(problems, ", ")
+	})
+}
+
+func leadingSpace(value string) string {
+	return value[:len(value)-len(strings.TrimLeftFunc(value, unicode.IsSpace))]
+}
+
+func trailingSpace(value string) string {
+	return value[len(strings.TrimRightFunc(value, unicode.IsSpace)):]
+}
+
+type doubleSpacesRule struct{}
+
+func (doubleSpacesRule) Name() string { return "double-spaces" }
+
+func (doubleSpacesRule) Description() string {
+	return "Translations don't have doubled spaces, unless the source does"
+}
+
+func (doubleSpacesRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	return checkValues(project, key, translation, func(value qaValue) string {
+		if strings.Contains(value.Value, "  ") && !strings.Contains(value.Source, "  ") {
+			return "has doubled spaces"
+		}
+		return ""
+	})
+}
+
+type punctuationRule struct{}
+
+func (punctuationRule) Name() string { return "punctuation" }
+
+func (punctuationRule) Description() string {
+	return "Translations end with the same punctuation as the source, e.g. a full stop or a question mark"
+}
+
+func (punctuationRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	return checkValues(project, key, translation, func(value qaValue) string {
+		if value.Source == "" {
+			return ""
+		}
+		actual := terminalPunctuation(value.Value)
+		expected := terminalPunctuation(value.Source)
+		switch {
+		case actual == expected:
+			return ""
+		case actual == "":
+			return fmt.Sprintf("doesn't end with %q like the source", expected)
+		case expected == "":
+			return fmt.Sprintf("ends with %q, the source doesn't", actual)
+		default:
+			return fmt.Sprintf("ends with %q, the source with %q", actual, expected)
+		}
+	})
+}
+
+// terminalPunctuationByRune maps the punctuation values end with to what it means, so a 。 matches a .
+var terminalPunctuationByRune = map[rune]string{
+	'.': ".", '。': ".", '।': ".", '۔': ".",
+	'!': "!", '！': "!",
+	'?': "?", '？': "?", '؟': "?", '\u037e': "?", // the greek question mark
+	':': ":", '：': ":",
+	'…': "…",
+}
+
+// terminalPunctuation is the punctuation value ends with, "" if it doesn't
+func terminalPunctuation(value string) string {
+	value = strings.TrimRightFunc(value, unicode.IsSpace)
+	if strings.HasSuffix(value, "...") {
+		return "…"
+	}
+	r, _ := utf8.DecodeLastRuneInString(value)
+	return terminalPunctuationByRune[r]
+}
+
+type identicalToSourceRule struct{}
+
+func (identicalToSourceRule) Name() string { return "identical-to-source" }
+
+func (identicalToSourceRule) Description() string {
+	return "Translations aren't the source value copied over, locales of the source's language can be"
+}
+
+func (identicalToSourceRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	if language(translation.Id) == language(project.SourceLocale) {
+		return nil
+	}
+	return checkValues(project, key, translation, func(value qaValue) string {
+		if value.Value == value.Source && strings.IndexFunc(withoutPlaceholders(value.Value), unicode.IsLetter) >= 0 {
+			return "is the same as the source, is it translated?"
+		}
+		return ""
+	})
+}
+
+// withoutPlaceholders is value with its placeholders taken out, the text of plural and select options stays
+func withoutPlaceholders(value string) string {
+	value = printfPlaceholder.ReplaceAllString(value, "")
+	value = mustachePlaceholder.ReplaceAllString(value, "")
+	return simpleArgument.ReplaceAllString(value, "")
+}
+
+// {name}, {count, number} and the like, arguments without options
+var simpleArgument = regexp.MustCompile(`\{\s*[\p{L}\p{N}_]+\s*(,[^{}]*)?\}`)
+
+// language is the language subtag of locale, e.g. en for en-GB
+func language(locale string) string {
+	return strings.ToLower(strings.SplitN(locale, "-", 2)[0])
+}
+
+type htmlTagsRule struct{}
+
+func (htmlTagsRule) Name() string { return "html-tags" }
+
+func (htmlTagsRule) Description() string {
+	return "Every HTML tag a translation opens is closed, in order"
+}
+
+func (htmlTagsRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	return checkValues(project, key, translation, func(value qaValue) string {
+		return unbalancedTag(value.Value)
+	})
+}
+
+var (
+	htmlTag = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)\b[^<>]*?(/?)>`)
+	// elements that never have a closing tag
+	voidElements = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr"}
+)
+
+// unbalancedTag explains the first tag in value that isn't balanced, "" when they all are
+func unbalancedTag(value string) string {
+	open := []string{}
+	for _, match := range htmlTag.FindAllStringSubmatch(value, -1) {
+		closing, name, selfClosing := match[1] == "/", strings.ToLower(match[2]), match[3] == "/"
+		switch {
+		case selfClosing || Contains(voidElements, name):
+		case !closing:
+			open = append(open, name)
+		case len(open) == 0:
+			return fmt.Sprintf("</%s> doesn't close anything", name)
+		case open[len(open)-1] != name:
+			return fmt.Sprintf("</%s> closes <%s>", name, open[len(open)-1])
+		default:
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Sprintf("<%s> is never closed", open[len(open)-1])
+	}
+	return ""
+}
+
+type maxLengthRule struct{}
+
+func (maxLengthRule) Name() string { return "max-length" }
+
+func (maxLengthRule) Description() string {
+	return "Translations fit the max length of their key"
+}
+
+// Check catches values from before the key had its max length, new ones are rejected when they're too long
+func (maxLengthRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	return checkValues(project, key, translation, func(value qaValue) string {
+		if isTooLong(value.Value, key.MaxLength) {
+			return fmt.Sprintf("is %d characters, the max length is %d", utf8.RuneCountInString(value.Value), key.MaxLength)
+		}
+		return ""
+	})
+}
+
+type glossaryRule struct{}
+
+func (glossaryRule) Name() string { return "glossary" }
+
+func (glossaryRule) Description() string {
+	return "Translations use the approved translation of the glossary terms in the source"
+}
+
+// Check only looks for the approved translation somewhere in the value, inflected or compound forms of it aren't found
+func (glossaryRule) Check(project *Project, key *Key, translation *Translation) *QAWarning {
+	if translation.Id == project.SourceLocale {
+		return nil
+	}
+	terms := project.Glossary()
+	return checkValues(project, key, translation, func(value qaValue) string {
+		problems := []string{}
+		for _, term := range terms {
+			target := term.Target(translation.Id)
+			if target == "" || !containsTerm(value.Source, term.Term) || strings.Contains(strings.ToLower(value.Value), strings.ToLower(target)) {
+				continue
+			}
+			if term.DoNotTranslate {
+				problems = append(problems, fmt.Sprintf("%q isn't translated, keep it as it is", term.Term))
+			} else {
+				problems = append(problems, fmt.Sprintf("%q should be translated as %q", term.Term, target))
+			}
+		}
+		return strings.Join(problems, ", ")
+	})
+}
+
+var (
+	// %s, %d, %1$s, %.2f... %% is a percent sign and is removed first, a space flag isn't allowed so "100% sure" isn't one
+	printfPlaceholder = regexp.MustCompile(`%(\d+\$)?[-+#0]*(\d+|\*)?(\.(\d+|\*))?[bcdeEfFgGoqsStTuvxX]`)
+	// {{name}}
+	mustachePlaceholder = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+	// {name} or the start of {name, type...}, for values that aren't valid MessageFormat
+	bracePlaceholder = regexp.MustCompile(`\{\s*([\p{L}\p{N}_]+)\s*[,}]`)
+)
+
+// valuePlaceholders counts the placeholders in value, printf ones by how often they're used, the named ones once
+func valuePlaceholders(value string) map[string]int {
+	counts := map[string]int{}
+	value = strings.ReplaceAll(value, "%%", "")
+	for _, placeholder := range printfPlaceholder.FindAllString(value, -1) {
+		counts[placeholder]++
+	}
+
+	for _, match := range mustachePlaceholder.FindAllStringSubmatch(value, -1) {
+		counts["{{"+match[1]+"}}"] = 1
+	}
+	value = mustachePlaceholder.ReplaceAllString(value, "")
+
+	message, err := ParseMessageFormat(value)
+	if err != nil {
+		for _, match := range bracePlaceholder.FindAllStringSubmatch(value, -1) {
+			counts["{"+match[1]+"}"] = 1
+		}
+		return counts
+	}
+	for _, name := range message.argumentNames() {
+		counts["{"+name+"}"] = 1
+	}
+	return counts
+}
+
+// translationPlaceholders are the placeholders of a translation, for plurals the most of each used by any category
+func translationPlaceholders(translation *Translation) map[string]int {
+	if !translation.Plural {
+		return valuePlaceholders(translation.Value)
+	}
+	counts := map[string]int{}
+	for _, value := range translation.Plurals {
+		for placeholder, count := range valuePlaceholders(value) {
+			counts[placeholder] = max(counts[placeholder], count)
+		}
+	}
+	return counts
+}
+
+// placeholdersNotIn returns the placeholders of a that b doesn't have as often, sorted
+func placeholdersNotIn(a map[string]int, b map[string]int) []string {
+	var result []string
+	for placeholder, count := range a {
+		if b[placeholder] < count {
+			result = append(result, placeholder)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// argumentNames returns the names of every argument in the message, including ones nested in plurals and selects
+func (o MessageFormat) argumentNames() []string {
+	names := []string{}
+	for _, part := range o {
+		if part.Argument == nil {
+			continue
+		}
+		names = append(names, part.Argument.Name)
+		for _, option := range part.Argument.Options {
+			names = append(names, option.Message.argumentNames()...)
+		}
+	}
+	return names
+}
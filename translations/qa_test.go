@@ -0,0 +1,156 @@
+package translations
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValuePlaceholders(t *testing.T) {
+	for value, expected := range map[string]map[string]int{
+		"Hello":                {},
+		"Hello %s, you are %d": {"%s": 1, "%d": 1},
+		"%s and %s":            {"%s": 2},
+		"%1$s %2$.2f":          {"%1$s": 1, "%2$.2f": 1},
+		"100% sure, 50%% off":  {},
+		"Hello {{ name }}":     {"{{name}}": 1},
+		"Hello {name}, {name}": {"{name}": 1},
+		"{count, plural, one {# item} other {# items for {name}}}": {"{count}": 1, "{name}": 1},
+		"'{name}' is quoted": {},
+		"broken {name":       {},
+		"broken } {name}":    {"{name}": 1},
+	} {
+		if actual := valuePlaceholders(value); !maps.Equal(actual, expected) {
+			t.Errorf("%q: expected %v, got %v", value, expected, actual)
+		}
+	}
+}
+
+func TestPlaceholdersRule(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+	results := NewQAResults(eventStore, DefaultQARules)
+	report := func() QAReport {
+		report, err := results.Report(ctx, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	if warnings := report().Warnings; len(warnings) != 0 {
+		t.Errorf("expected no warnings without placeholders, got %v", warnings)
+	}
+
+	// p1's source locale is es
+	eventStore.mustApply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "es", Value: "Hola {name}, %s"}))
+	eventStore.mustApply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello {nmae}"}))
+	warnings := report().Warnings
+	if len(warnings) != 1 {
+		t.Fatalf("expected a warning for en, got %v", warnings)
+	}
+	if warnings[0].Locale != "en" || !slices.Equal(warnings[0].Missing, []string{"%s", "{name}"}) || !slices.Equal(warnings[0].Extra, []string{"{nmae}"}) {
+		t.Errorf("expected en to miss %%s and {name} and have {nmae}, got %+v", warnings[0])
+	}
+
+	// plural translations only need each placeholder in one of their categories
+	eventStore.mustApply(SetKeyPlural(eventStore)(ctx, SetKeyPluralInput{ProjectId: "p1", Id: "header_1", Plural: true}))
+	eventStore.mustApply(UpdatePluralTranslation(eventStore)(ctx, UpdatePluralTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Plurals: map[PluralCategory]string{
+		PluralOne:   "Hello {name}",
+		PluralOther: "Hello {name}, %s",
+	}}))
+	if warnings := report().Warnings; len(warnings) != 0 {
+		t.Errorf("expected the plural en to match, got %v", warnings)
+	}
+}
+
+func TestQARules(t *testing.T) {
+	for _, test := range []struct {
+		rule    QARule
+		source  string
+		value   string
+		warning bool
+	}{
+		{whitespaceRule{}, "Hello ", "Hallo ", false},
+		{whitespaceRule{}, "Hello", " Hallo", true},
+		{whitespaceRule{}, "Hello\n", "Hallo", true},
+		{doubleSpacesRule{}, "Hello there", "Hallo  da", true},
+		{doubleSpacesRule{}, "Hello  there", "Hallo  da", false},
+		{punctuationRule{}, "Done.", "Fertig.", false},
+		{punctuationRule{}, "Done.", "完了。", false},
+		{punctuationRule{}, "Done?", "Fertig", true},
+		{punctuationRule{}, "Loading...", "Laden…", false},
+		{punctuationRule{}, "Done", "Fertig!", true},
+		{identicalToSourceRule{}, "Hello", "Hello", true},
+		{identicalToSourceRule{}, "%s", "%s", false},
+		{identicalToSourceRule{}, "{count} ({percent, number})", "{count} ({percent, number})", false},
+		{identicalToSourceRule{}, "{n, plural, one {# item} other {# items}}", "{n, plural, one {# item} other {# items}}", true},
+		{identicalToSourceRule{}, "Hello", "Hallo", false},
+		{htmlTagsRule{}, "<b>Hello</b>", "<b>Hallo</b><br>", false},
+		{htmlTagsRule{}, "<b>Hello</b>", "<b>Hallo", true},
+		{htmlTagsRule{}, "<b><i>Hello</i></b>", "<b><i>Hallo</b></i>", true},
+		{htmlTagsRule{}, "Hello", "Hallo</p>", true},
+	} {
+		project := &Project{SourceLocale: "en"}
+		key := &Key{Id: "greeting", TranslationsById: map[string]*Translation{
+			"en": {Id: "en", Value: test.source},
+			"de": {Id: "de", Value: test.value},
+		}}
+		warning := test.rule.Check(project, key, key.TranslationsById["de"])
+		if (warning != nil) != test.warning {
+			t.Errorf("%s %q -> %q: expected a warning %v, got %+v", test.rule.Name(), test.source, test.value, test.warning, warning)
+		}
+	}
+
+	key := &Key{Id: "greeting", KeyMetadata: KeyMetadata{MaxLength: 5}, TranslationsById: map[string]*Translation{
+		"en": {Id: "en", Value: "Hello"},
+		"de": {Id: "de", Plural: true, Plurals: map[PluralCategory]string{PluralOne: "Hallo", PluralOther: "Hallöchen"}},
+	}}
+	warning := maxLengthRule{}.Check(&Project{SourceLocale: "en"}, key, key.TranslationsById["de"])
+	if warning == nil || !strings.HasPrefix(warning.Message, "other: ") {
+		t.Errorf("expected the other category to be too long, got %+v", warning)
+	}
+}
+
+func TestQAResults(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+	results := NewQAResults(eventStore, DefaultQARules)
+	checks := func() []string {
+		report, err := results.Report(ctx, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checks := []string{}
+		for _, warning := range report.Warnings {
+			checks = append(checks, warning.Check)
+		}
+		return checks
+	}
+
+	// p1's source locale is es
+	eventStore.mustApply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hola"}))
+	if actual := checks(); !slices.Equal(actual, []string{"identical-to-source"}) {
+		t.Errorf("expected en to be flagged as identical to the source, got %v", actual)
+	}
+
+	eventStore.mustApply(SetQARuleEnabled(eventStore, DefaultQARules)(ctx, SetQARuleEnabledInput{ProjectId: "p1", Rule: "identical-to-source", Enabled: false}))
+	if actual := checks(); len(actual) != 0 {
+		t.Errorf("expected no warnings with the rule disabled, got %v", actual)
+	}
+
+	eventStore.mustApply(SetQARuleEnabled(eventStore, DefaultQARules)(ctx, SetQARuleEnabledInput{ProjectId: "p1", Rule: "identical-to-source", Enabled: true}))
+	eventStore.mustApply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello"}))
+	if actual := checks(); len(actual) != 0 {
+		t.Errorf("expected the warning to go away once en is translated, got %v", actual)
+	}
+
+	var validation ValidationError
+	_, err := SetQARuleEnabled(eventStore, DefaultQARules)(ctx, SetQARuleEnabledInput{ProjectId: "p1", Rule: "spelling"})
+	if !errors.As(err, &validation) || validation.Fields["Rule"] == "" {
+		t.Errorf("expected an unknown rule to be rejected, got %v", err)
+	}
+}
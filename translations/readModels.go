@@ -3,7 +3,9 @@ package translations
 import (
 	"context"
 	"database/sql"
+	"sort"
 	"sync"
+	"time"
 )
 
 type InMemoryProjectList struct {
@@ -40,3 +42,353 @@ func (o *InMemoryProjectList) Handle(ctx context.Context, tx *sql.Tx, event Even
 	o.projectsById[event.GetAggregateId()].Reduce(event)
 	return nil
 }
+
+type CommentThread struct {
+	Id        string
+	ProjectId string
+	KeyId     string // "" for threads about the whole project
+	Locale    string // "" for threads about the whole key
+
+	DateCreated time.Time
+	DateUpdated time.Time
+	Resolved    bool
+
+	Comments []Comment
+}
+
+type Comment struct {
+	Id          string
+	AuthorId    string
+	Author      string // username
+	Text        string
+	DateCreated time.Time
+	DateUpdated time.Time
+	Edited      bool
+}
+
+// commentThreadsEventTypes are the events CommentThreads is built from, keys and locales going away take their threads with them
+var commentThreadsEventTypes = []string{
+	TypeName(CommentAdded{}),
+	TypeName(CommentEdited{}),
+	TypeName(CommentResolved{}),
+	TypeName(UserRegistered{}),
+	TypeName(ProjectDeleted{}),
+	TypeName(LocaleRemoved{}),
+	TypeName(KeyDeleted{}),
+	TypeName(KeyRenamed{}),
+	TypeName(KeyMoved{}),
+}
+
+/*
+CommentThreads
+- read model of the comment threads of every project
+- catches up with the event store whenever it's read, so it always includes everything written before the read
+*/
+type CommentThreads struct {
+	eventStore EventStore
+
+	mu              sync.Mutex
+	position        int64
+	threadsById     map[string]*CommentThread
+	usernamesByUser map[string]string
+}
+
+func NewCommentThreads(eventStore EventStore) *CommentThreads {
+	return &CommentThreads{
+		eventStore:      eventStore,
+		threadsById:     map[string]*CommentThread{},
+		usernamesByUser: map[string]string{},
+	}
+}
+
+// OpenThreads returns the unresolved threads of a project, most recently active first
+func (o *CommentThreads) OpenThreads(ctx context.Context, projectId string) ([]CommentThread, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := o.catchUp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := []CommentThread{}
+	for _, thread := range o.threadsById {
+		if thread.ProjectId == projectId && !thread.Resolved {
+			threads = append(threads, thread.clone())
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool { return threads[i].DateUpdated.After(threads[j].DateUpdated) })
+	return threads, nil
+}
+
+// OpenThread returns the unresolved thread about a key in a locale, nil if there isn't one
+func (o *CommentThreads) OpenThread(ctx context.Context, projectId string, keyId string, locale string) (*CommentThread, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := o.catchUp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, thread := range o.threadsById {
+		if thread.ProjectId == projectId && thread.KeyId == keyId && thread.Locale == locale && !thread.Resolved {
+			clone := thread.clone()
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+// Thread returns the thread with id, nil if there isn't one
+func (o *CommentThreads) Thread(ctx context.Context, id string) (*CommentThread, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := o.catchUp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, ok := o.threadsById[id]
+	if !ok {
+		return nil, nil
+	}
+	clone := thread.clone()
+	return &clone, nil
+}
+
+func (o *CommentThreads) catchUp(ctx context.Context) error {
+	return ReduceWith(ctx, o, o.eventStore.NewGenerator(EventTypes(commentThreadsEventTypes...), AfterPosition(o.position)))
+}
+
+func (o *CommentThreads) Reduce(event Event) {
+	o.position = event.GetPosition()
+
+	switch e := event.(type) {
+	case UserRegistered:
+		o.usernamesByUser[e.Id] = e.Username
+	case CommentAdded:
+		thread, ok := o.threadsById[e.ThreadId]
+		if !ok {
+			thread = &CommentThread{
+				Id:          e.ThreadId,
+				ProjectId:   e.ProjectId,
+				KeyId:       e.KeyId,
+				Locale:      e.Locale,
+				DateCreated: e.Timestamp,
+			}
+			o.threadsById[e.ThreadId] = thread
+		}
+		thread.DateUpdated = e.Timestamp
+		thread.Comments = append(thread.Comments, Comment{
+			Id:          e.Id,
+			AuthorId:    e.Actor,
+			Author:      o.username(e.Actor),
+			Text:        e.Text,
+			DateCreated: e.Timestamp,
+			DateUpdated: e.Timestamp,
+		})
+	case CommentEdited:
+		thread, ok := o.threadsById[e.ThreadId]
+		if !ok {
+			break
+		}
+		for i := range thread.Comments {
+			if thread.Comments[i].Id == e.Id {
+				thread.Comments[i].Text = e.Text
+				thread.Comments[i].DateUpdated = e.Timestamp
+				thread.Comments[i].Edited = true
+			}
+		}
+	case CommentResolved:
+		thread, ok := o.threadsById[e.ThreadId]
+		if !ok {
+			break
+		}
+		thread.Resolved = true
+		thread.DateUpdated = e.Timestamp
+	case ProjectDeleted:
+		o.deleteThreads(func(thread *CommentThread) bool { return thread.ProjectId == e.Id })
+	case LocaleRemoved:
+		o.deleteThreads(func(thread *CommentThread) bool { return thread.ProjectId == e.ProjectId && thread.Locale == e.Locale })
+	case KeyDeleted:
+		o.deleteThreads(func(thread *CommentThread) bool { return thread.ProjectId == e.ProjectId && thread.KeyId == e.Id })
+	case KeyRenamed:
+		for _, thread := range o.threadsById {
+			if thread.ProjectId == e.ProjectId && thread.KeyId == e.Id {
+				thread.KeyId = e.NewId
+			}
+		}
+	case KeyMoved:
+		// written to both projects, only follow the key once
+		if e.GetAggregateId() != e.ToProjectId {
+			break
+		}
+		for _, thread := range o.threadsById {
+			if thread.ProjectId == e.FromProjectId && thread.KeyId == e.Id {
+				thread.ProjectId = e.ToProjectId
+			}
+		}
+	}
+}
+
+func (o *CommentThreads) deleteThreads(match func(thread *CommentThread) bool) {
+	for id, thread := range o.threadsById {
+		if match(thread) {
+			delete(o.threadsById, id)
+		}
+	}
+}
+
+func (o *CommentThreads) username(userId string) string {
+	if username, ok := o.usernamesByUser[userId]; ok {
+		return username
+	}
+	return userId
+}
+
+func (o *CommentThread) clone() CommentThread {
+	clone := *o
+	clone.Comments = append([]Comment{}, o.Comments...)
+	return clone
+}
+
+// qaResultsEventTypes are the events QAResults is built from, everything that changes a project's keys, values,
+// locales, rules or glossary
+var qaResultsEventTypes = []string{
+	TypeName(ProjectCreated{}),
+	TypeName(ProjectDeleted{}),
+	TypeName(LocaleAdded{}),
+	TypeName(LocaleRemoved{}),
+	TypeName(SourceLocaleChanged{}),
+	TypeName(KeyCreated{}),
+	TypeName(KeyDeleted{}),
+	TypeName(KeyRenamed{}),
+	TypeName(KeyMoved{}),
+	TypeName(KeyMetadataUpdated{}),
+	TypeName(KeyPluralChanged{}),
+	TypeName(TranslationUpdated{}),
+	TypeName(PluralTranslationUpdated{}),
+	TypeName(TranslationDeleted{}),
+	TypeName(QARuleEnabled{}),
+	TypeName(QARuleDisabled{}),
+	TypeName(GlossaryTermAdded{}),
+	TypeName(GlossaryTermUpdated{}),
+	TypeName(GlossaryTermRemoved{}),
+}
+
+/*
+QAResults
+- read model of the QA warnings of every project, the rules run again on a key whenever its values change
+- keeps its own copy of every project to run the rules against
+- Report runs the rules on whatever was written since the last report first, so a value saved right before is already checked
+*/
+type QAResults struct {
+	eventStore EventStore
+	rules      *QARules
+
+	mu                sync.Mutex
+	position          int64
+	projectsById      map[string]*Project
+	warningsByProject map[string]map[string][]QAWarning // by project id, then key id
+}
+
+func NewQAResults(eventStore EventStore, rules *QARules) *QAResults {
+	return &QAResults{
+		eventStore:        eventStore,
+		rules:             rules,
+		projectsById:      map[string]*Project{},
+		warningsByProject: map[string]map[string][]QAWarning{},
+	}
+}
+
+// Report returns the warnings of a project, ordered by key then by the project's locale order
+func (o *QAResults) Report(ctx context.Context, projectId string) (QAReport, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := o.catchUp(ctx)
+	if err != nil {
+		return QAReport{}, err
+	}
+
+	warningsByKey := o.warningsByProject[projectId]
+	keyIds := make([]string, 0, len(warningsByKey))
+	for keyId := range warningsByKey {
+		keyIds = append(keyIds, keyId)
+	}
+	sort.Strings(keyIds)
+
+	report := QAReport{ProjectId: projectId, Warnings: []QAWarning{}}
+	for _, keyId := range keyIds {
+		report.Warnings = append(report.Warnings, warningsByKey[keyId]...)
+	}
+	return report, nil
+}
+
+func (o *QAResults) catchUp(ctx context.Context) error {
+	return ReduceWith(ctx, o, o.eventStore.NewGenerator(EventTypes(qaResultsEventTypes...), AfterPosition(o.position)))
+}
+
+func (o *QAResults) Reduce(event Event) {
+	o.position = event.GetPosition()
+
+	project, ok := o.projectsById[event.GetAggregateId()]
+	if !ok {
+		project = &Project{}
+		o.projectsById[event.GetAggregateId()] = project
+	}
+	project.Reduce(event)
+
+	switch e := event.(type) {
+	case ProjectDeleted:
+		delete(o.projectsById, e.Id)
+		delete(o.warningsByProject, e.Id)
+	case KeyCreated:
+		o.check(project, e.Id)
+	case KeyDeleted:
+		o.check(project, e.Id)
+	case KeyRenamed:
+		o.check(project, e.Id)
+		o.check(project, e.NewId)
+	case KeyMoved:
+		// written to both projects, the key is gone from one and arrived in the other
+		o.check(project, e.Id)
+	case KeyMetadataUpdated:
+		o.check(project, e.Id)
+	case KeyPluralChanged:
+		o.check(project, e.Id)
+	case TranslationUpdated:
+		o.check(project, e.KeyId)
+	case PluralTranslationUpdated:
+		o.check(project, e.KeyId)
+	case TranslationDeleted:
+		o.check(project, e.KeyId)
+	default:
+		// locales, the source locale, the project's rules and glossary change what every key is checked against
+		for keyId := range project.KeysById {
+			o.check(project, keyId)
+		}
+		for keyId := range o.warningsByProject[project.Id] {
+			o.check(project, keyId)
+		}
+	}
+}
+
+// check runs the rules on a key of project again, a key that's gone has no warnings
+func (o *QAResults) check(project *Project, keyId string) {
+	warningsByKey, ok := o.warningsByProject[project.Id]
+	if !ok {
+		warningsByKey = map[string][]QAWarning{}
+		o.warningsByProject[project.Id] = warningsByKey
+	}
+
+	key, ok := project.KeysById[keyId]
+	if !ok {
+		delete(warningsByKey, keyId)
+		return
+	}
+	warnings := project.CheckKey(o.rules, key)
+	if len(warnings) == 0 {
+		delete(warningsByKey, keyId)
+		return
+	}
+	warningsByKey[keyId] = warnings
+}
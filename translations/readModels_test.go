@@ -0,0 +1,79 @@
+package translations
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCommentThreads(t *testing.T) {
+	ctx := WithActor(context.Background(), "u1")
+	eventStore := newTestEventStore(t, append(greetingProject(), UserRegistered{EventBase: NewEventBase(ctx, "u1"), Id: "u1", Username: "alice"})...)
+	threads := NewCommentThreads(eventStore)
+
+	for _, text := range []string{"Too formal?", "Agreed"} {
+		eventStore.mustApply(AddComment(eventStore, threads)(ctx, AddCommentInput{ProjectId: "p1", KeyId: "header_1", Locale: "es", Text: text}))
+	}
+	open, err := threads.OpenThreads(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || len(open[0].Comments) != 2 || open[0].Comments[0].Author != "alice" {
+		t.Fatalf("expected both comments in one thread by alice, got %+v", open)
+	}
+	thread := open[0]
+
+	var validation ValidationError
+	err = eventStore.apply(EditComment(threads)(WithActor(ctx, "u2"), EditCommentInput{ProjectId: "p1", ThreadId: thread.Id, Id: thread.Comments[0].Id, Text: "Too informal?"}))
+	if !errors.As(err, &validation) {
+		t.Errorf("expected only the author to be able to edit, got %v", err)
+	}
+	eventStore.mustApply(EditComment(threads)(ctx, EditCommentInput{ProjectId: "p1", ThreadId: thread.Id, Id: thread.Comments[0].Id, Text: "Too informal?"}))
+
+	eventStore.mustApply(RenameKey(eventStore)(ctx, RenameKeyInput{ProjectId: "p1", Id: "header_1", NewId: "home.title"}))
+	renamed, err := threads.OpenThread(ctx, "p1", "home.title", "es")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed == nil || renamed.Id != thread.Id || renamed.Comments[0].Text != "Too informal?" || !renamed.Comments[0].Edited {
+		t.Errorf("expected the edited thread to follow the key, got %+v", renamed)
+	}
+
+	eventStore.mustApply(ResolveCommentThread(threads)(ctx, ResolveCommentThreadInput{ProjectId: "p1", Id: thread.Id}))
+	eventStore.mustApply(AddComment(eventStore, threads)(ctx, AddCommentInput{ProjectId: "p1", KeyId: "home.title", Locale: "es", Text: "Changed again"}))
+	open, err = threads.OpenThreads(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open[0].Id == thread.Id {
+		t.Errorf("expected a comment after resolving to start a new thread, got %+v", open)
+	}
+}
+
+func TestCommentThreadSubjects(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+	threads := NewCommentThreads(eventStore)
+
+	// the project, the key and each of its translations have a thread of their own
+	for _, input := range []AddCommentInput{
+		{ProjectId: "p1", Text: "Who's reviewing es?"},
+		{ProjectId: "p1", KeyId: "header_1", Text: "Shown on every page"},
+		{ProjectId: "p1", KeyId: "header_1", Locale: "en", Text: "Too formal?"},
+	} {
+		eventStore.mustApply(AddComment(eventStore, threads)(ctx, input))
+		thread, err := threads.OpenThread(ctx, input.ProjectId, input.KeyId, input.Locale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if thread == nil || len(thread.Comments) != 1 || thread.Comments[0].Text != input.Text {
+			t.Errorf("expected a thread of its own for %+v, got %+v", input, thread)
+		}
+	}
+
+	var validation ValidationError
+	_, err := AddComment(eventStore, threads)(ctx, AddCommentInput{ProjectId: "p1", Locale: "en", Text: "All of en"})
+	if !errors.As(err, &validation) || validation.Fields["Locale"] == "" {
+		t.Errorf("expected a thread about a locale without a key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package translations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/*
+Registry
+- maps stable names to the types that get serialized under them, so renaming or moving a struct doesn't orphan stored data
+- a type can also be found by aliases, e.g. names it was stored under before it was registered
+- Serialize and Deserialize use DefaultRegistry
+*/
+type Registry struct {
+	mu              sync.RWMutex
+	typesByName     map[string]reflect.Type
+	namesByType     map[reflect.Type]string
+	upcastersByName map[string][]Upcaster
+}
+
+var (
+	ErrorDuplicateName = errors.New("name already registered")
+	ErrorUnknownName   = errors.New("no type registered under name")
+	ErrorUnregistered  = errors.New("type not registered")
+	ErrorDuplicateType = errors.New("type already registered")
+)
+
+func NewRegistry() *Registry {
+	return &Registry{
+		typesByName:     map[string]reflect.Type{},
+		namesByType:     map[reflect.Type]string{},
+		upcastersByName: map[string][]Upcaster{},
+	}
+}
+
+func (o *Registry) Register(name string, prototype any, aliases ...string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	rType := reflect.TypeOf(prototype)
+	if _, ok := o.namesByType[rType]; ok {
+		return fmt.Errorf("%w: %s", ErrorDuplicateType, rType)
+	}
+	for _, n := range append([]string{name}, aliases...) {
+		if _, ok := o.typesByName[n]; ok {
+			return fmt.Errorf("%w: %s", ErrorDuplicateName, n)
+		}
+	}
+
+	o.namesByType[rType] = name
+	for _, n := range append([]string{name}, aliases...) {
+		o.typesByName[n] = rType
+	}
+	return nil
+}
+
+func (o *Registry) MustRegister(name string, prototype any, aliases ...string) {
+	err := o.Register(name, prototype, aliases...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Name is the name data's type is registered under
+func (o *Registry) Name(data any) (string, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	name, ok := o.namesByType[reflect.TypeOf(data)]
+	if !ok {
+		return "", fmt.Errorf("%w: %T", ErrorUnregistered, data)
+	}
+	return name, nil
+}
+
+type TypeWrapper struct {
+	TypeName string `json:"typeName"`
+	Version  int    `json:"version,omitempty"` // schema version of the payload, see RegisterUpcaster
+	Payload  string `json:"payload"`
+}
+
+func (o *Registry) Serialize(data any) (string, error) {
+	name, err := o.Name(data)
+	if err != nil {
+		return "", err
+	}
+
+	serializedPayload, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	serializedTypeWrapper, err := json.Marshal(TypeWrapper{
+		TypeName: name,
+		Version:  o.SchemaVersion(name),
+		Payload:  string(serializedPayload),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(serializedTypeWrapper), nil
+}
+
+func (o *Registry) Deserialize(data string) (any, error) {
+	var typeWrapper TypeWrapper
+	err := json.Unmarshal([]byte(data), &typeWrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	rType, ok := o.typesByName[typeWrapper.TypeName]
+	name := o.namesByType[rType]
+	o.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownName, typeWrapper.TypeName)
+	}
+
+	payload, err := o.upcast(name, typeWrapper.Version, typeWrapper.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	rTargetPtr := reflect.New(rType)                              // this is like t := &T{}
+	err = json.Unmarshal([]byte(payload), rTargetPtr.Interface()) // this is like t
+	if err != nil {
+		return nil, err
+	}
+
+	return rTargetPtr.Elem().Interface(), nil // this is like *t
+}
+
+// TypeName is the name data is registered under in DefaultRegistry, or "" if it isn't
+func TypeName(data any) string {
+	name, _ := DefaultRegistry.Name(data)
+	return name
+}
+
+func Serialize(data any) (string, error) {
+	return DefaultRegistry.Serialize(data)
+}
+
+func Deserialize(data string) (any, error) {
+	return DefaultRegistry.Deserialize(data)
+}
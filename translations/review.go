@@ -0,0 +1,70 @@
+package translations
+
+import "fmt"
+
+/*
+TranslationStatus
+- where a translation is in review, see Translation.Status
+- untranslated -> draft -> needs review -> approved or rejected, editing a translation makes it a draft again
+- editing the source locale's value sends every other translation with a value back to needs review
+*/
+type TranslationStatus string
+
+const (
+	StatusUntranslated TranslationStatus = "untranslated"
+	StatusDraft        TranslationStatus = "draft"
+	StatusNeedsReview  TranslationStatus = "needs-review"
+	StatusApproved     TranslationStatus = "approved"
+	StatusRejected     TranslationStatus = "rejected"
+)
+
+// TranslationStatuses are all the statuses, in workflow order
+var TranslationStatuses = []TranslationStatus{StatusUntranslated, StatusDraft, StatusNeedsReview, StatusApproved, StatusRejected}
+
+// ParseTranslationStatus returns the status named s, ok is false when there's no such status
+func ParseTranslationStatus(s string) (TranslationStatus, bool) {
+	for _, status := range TranslationStatuses {
+		if string(status) == s {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+// valueStatus is the status of a translation that was just given its value
+func (o *Translation) valueStatus() TranslationStatus {
+	if !o.hasValue() {
+		return StatusUntranslated
+	}
+	return StatusDraft
+}
+
+// reviewTransitions are the statuses a translation can be moved to by review, and the ones it can be moved from
+var reviewTransitions = map[TranslationStatus][]TranslationStatus{
+	StatusNeedsReview: {StatusDraft, StatusRejected},
+	StatusApproved:    {StatusDraft, StatusNeedsReview},
+	StatusRejected:    {StatusDraft, StatusNeedsReview, StatusApproved},
+}
+
+// CanMoveTo reports whether review can move the translation to status
+func (o *Translation) CanMoveTo(status TranslationStatus) bool {
+	return Contains(reviewTransitions[status], o.Status)
+}
+
+// validateReview checks translation can be moved to status
+func validateReview(validation *ValidationError, translation *Translation, status TranslationStatus) {
+	if !translation.CanMoveTo(status) {
+		validation.Add("Status", fmt.Sprintf("a translation that is %s can't be %s", translation.Status.Label(), status.Label()))
+	}
+}
+
+// Label is how a status is shown to people
+func (o TranslationStatus) Label() string {
+	switch o {
+	case StatusNeedsReview:
+		return "needs review"
+	case "":
+		return string(StatusUntranslated)
+	}
+	return string(o)
+}
@@ -0,0 +1,50 @@
+package translations
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTranslationReview(t *testing.T) {
+	ctx := context.Background()
+	eventStore := newTestEventStore(t, greetingProject()...)
+	status := func(locale string) TranslationStatus {
+		project, err := GetProject(ctx, eventStore, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return project.KeysById["header_1"].TranslationsById[locale].Status
+	}
+
+	if status("en") != StatusDraft {
+		t.Errorf("expected a translation with a value to start as a draft, got %s", status("en"))
+	}
+
+	eventStore.mustApply(SubmitTranslation(eventStore)(ctx, SubmitTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"}))
+	eventStore.mustApply(ApproveTranslation(eventStore)(ctx, ApproveTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"}))
+	if status("en") != StatusApproved {
+		t.Errorf("expected en to be approved, got %s", status("en"))
+	}
+
+	var validation ValidationError
+	err := eventStore.apply(SubmitTranslation(eventStore)(ctx, SubmitTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "en"}))
+	if !errors.As(err, &validation) || validation.Fields["Status"] == "" {
+		t.Errorf("expected an approved translation to not be submittable, got %v", err)
+	}
+	err = eventStore.apply(RejectTranslation(eventStore)(ctx, RejectTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "es"}))
+	if !errors.As(err, &validation) || validation.Fields["Reason"] == "" {
+		t.Errorf("expected a rejection to need a reason, got %v", err)
+	}
+
+	// p1's source locale is es
+	eventStore.mustApply(ApproveTranslation(eventStore)(ctx, ApproveTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "es"}))
+	eventStore.mustApply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "es", Value: "Hola"}))
+	if status("es") != StatusApproved || status("en") != StatusApproved {
+		t.Errorf("expected saving the same value to change nothing, got es %s and en %s", status("es"), status("en"))
+	}
+	eventStore.mustApply(UpdateTranslation(eventStore)(ctx, UpdateTranslationInput{ProjectId: "p1", KeyId: "header_1", Id: "es", Value: "¡Hola!"}))
+	if status("es") != StatusDraft || status("en") != StatusNeedsReview {
+		t.Errorf("expected editing the source to send en back to review, got es %s and en %s", status("es"), status("en"))
+	}
+}
@@ -0,0 +1,102 @@
+package translations
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+Snapshots
+- a serialized aggregate along with the version and position of the last event reduced into it
+- event stores that also implement SnapshotStore let GetProject skip replaying the whole stream
+*/
+type Snapshot struct {
+	AggregateId string
+	// Schema is the shape of the aggregate the snapshot was taken with, snapshots with an old schema are ignored
+	Schema   int
+	Version  int
+	Position int64
+	Data     string
+}
+
+type SnapshotStore interface {
+	// LoadSnapshot returns nil when there's no snapshot for the aggregate
+	LoadSnapshot(ctx context.Context, aggregateId string) (*Snapshot, error)
+	SaveSnapshot(ctx context.Context, snapshot Snapshot) error
+}
+
+// projectSnapshotSchema has to be bumped whenever Project or Project.Reduce changes,
+// otherwise projects get rebuilt from snapshots taken with the old behavior
+const projectSnapshotSchema = 11
+
+// projectSnapshotInterval is how many events can be written to a project past its last snapshot before a new one is taken
+const projectSnapshotInterval = 100
+
+// readProject reduces a project onto its latest snapshot, snapshotVersion is the version the snapshot was taken at, 0 without one
+func readProject(ctx context.Context, eventStore EventStore, id string) (project Project, snapshotVersion int, err error) {
+	snapshotStore, hasSnapshots := eventStore.(SnapshotStore)
+	if hasSnapshots {
+		project, err = loadProjectSnapshot(ctx, snapshotStore, id)
+		if err != nil {
+			return project, 0, err
+		}
+	}
+	snapshotVersion = project.Version
+
+	err = ReduceWith(ctx, &project, eventStore.NewGenerator(AggregateIds(id), AfterPosition(project.Position)))
+	return project, snapshotVersion, err
+}
+
+// saveProjectSnapshotIfDue snapshots project id once projectSnapshotInterval events were written past its last snapshot,
+// NewCommandPipeline runs it after writing so reading a project never has to write
+func saveProjectSnapshotIfDue(ctx context.Context, eventStore EventStore, id string) error {
+	snapshotStore, ok := eventStore.(SnapshotStore)
+	if !ok {
+		return nil
+	}
+	project, snapshotVersion, err := readProject(ctx, eventStore, id)
+	if err != nil {
+		return err
+	}
+	// other aggregates, like the users stream, reduce to a project without an id
+	if project.Id == "" || project.Version-snapshotVersion < projectSnapshotInterval {
+		return nil
+	}
+	return saveProjectSnapshot(ctx, snapshotStore, &project)
+}
+
+func loadProjectSnapshot(ctx context.Context, snapshotStore SnapshotStore, id string) (Project, error) {
+	var project Project
+	snapshot, err := snapshotStore.LoadSnapshot(ctx, id)
+	if err != nil {
+		return project, err
+	}
+	if snapshot == nil || snapshot.Schema != projectSnapshotSchema {
+		return project, nil
+	}
+
+	deserialized, err := Deserialize(snapshot.Data)
+	if err != nil {
+		return project, err
+	}
+	project, ok := deserialized.(Project)
+	if !ok {
+		return project, fmt.Errorf("snapshot of %s: %T is not a Project", id, deserialized)
+	}
+	return project, nil
+}
+
+func saveProjectSnapshot(ctx context.Context, snapshotStore SnapshotStore, project *Project) error {
+	data, err := Serialize(*project)
+	if err != nil {
+		return err
+	}
+
+	return snapshotStore.SaveSnapshot(ctx, Snapshot{
+		AggregateId: project.Id,
+		Schema:      projectSnapshotSchema,
+		Version:     project.Version,
+		Position:    project.Position,
+		Data:        data,
+	})
+}
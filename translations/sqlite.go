@@ -0,0 +1,75 @@
+package translations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+/*
+Migrations
+- each named group of statements is applied once, in order, and recorded in the migrations table
+- never edit a migration that has shipped, append a new one instead
+*/
+type migration struct {
+	Id         string
+	Statements []string
+}
+
+func migrate(ctx context.Context, db *sql.DB, migrations []migration) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS migrations (id TEXT PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		err := applyMigration(ctx, db, m)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.Id, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM migrations WHERE id = ?`, m.Id).Scan(&count)
+	if err != nil {
+		mustRollback(tx)
+		return err
+	}
+	if count > 0 {
+		mustRollback(tx)
+		return nil
+	}
+
+	for _, statement := range m.Statements {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			mustRollback(tx)
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO migrations (id) VALUES (?)`, m.Id)
+	if err != nil {
+		mustRollback(tx)
+		return err
+	}
+	return tx.Commit()
+}
+
+func placeholders(n int) string {
+	s := ""
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			s += ", "
+		}
+		s += "?"
+	}
+	return s
+}
@@ -0,0 +1,320 @@
+package translations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const sqliteGeneratorBatchSize = 100
+
+var sqliteEventStoreMigrations = []migration{
+	{
+		Id: "events_1",
+		Statements: []string{
+			`CREATE TABLE events (
+				position INTEGER PRIMARY KEY AUTOINCREMENT,
+				aggregate_id TEXT NOT NULL,
+				type TEXT NOT NULL,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX events_aggregate_id ON events (aggregate_id, position)`,
+		},
+	},
+	{
+		Id: "events_2",
+		Statements: []string{
+			`ALTER TABLE events ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE events SET version = (
+				SELECT COUNT(*) FROM events AS e
+				WHERE e.aggregate_id = events.aggregate_id AND e.position <= events.position
+			)`,
+			`CREATE UNIQUE INDEX events_aggregate_id_version ON events (aggregate_id, version)`,
+		},
+	},
+	{
+		Id: "events_3",
+		Statements: []string{
+			`CREATE INDEX events_type ON events (type, position)`,
+		},
+	},
+	{
+		// events are stored under their registered names rather than their Go type names
+		Id: "events_4",
+		Statements: []string{
+			`UPDATE events SET type = substr(type, length('translations.') + 1) WHERE type LIKE 'translations.%'`,
+		},
+	},
+	{
+		Id: "events_5",
+		Statements: []string{
+			`ALTER TABLE events ADD COLUMN event_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE events ADD COLUMN correlation_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE events ADD COLUMN causation_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX events_correlation_id ON events (correlation_id, position)`,
+			`CREATE INDEX events_causation_id ON events (causation_id, position)`,
+		},
+	},
+	{
+		Id: "snapshots_1",
+		Statements: []string{
+			`CREATE TABLE snapshots (
+				aggregate_id TEXT PRIMARY KEY,
+				schema INTEGER NOT NULL,
+				version INTEGER NOT NULL,
+				position INTEGER NOT NULL,
+				data TEXT NOT NULL
+			)`,
+		},
+	},
+}
+
+/*
+SQLiteDSN
+- the data source name to open the database at path with for a SQLiteEventStore
+- WAL lets readers carry on while someone writes, and writers wait up to the busy timeout for each other instead of failing with "database is locked"
+- transactions take the write lock when they begin, a deferred one that reads a version first can't wait for it once another writer has committed
+*/
+func SQLiteDSN(path string) string {
+	return fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate", path)
+}
+
+type SQLiteEventStore struct {
+	notifier
+
+	db *sql.DB
+}
+
+func NewSQLiteEventStore(db *sql.DB) (*SQLiteEventStore, error) {
+	err := migrate(context.Background(), db, sqliteEventStoreMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLiteEventStore{
+		db: db,
+	}, nil
+}
+
+func (o *SQLiteEventStore) Write(ctx context.Context, tx *sql.Tx, expectedVersion int, events ...Event) error {
+	if tx != nil {
+		return o.insertAll(ctx, tx, expectedVersion, events)
+	}
+
+	tx, err := o.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	err = o.insertAll(ctx, tx, expectedVersion, events)
+	if err != nil {
+		mustRollback(tx)
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	o.notify()
+	return nil
+}
+
+func (o *SQLiteEventStore) insertAll(ctx context.Context, tx *sql.Tx, expectedVersion int, events []Event) error {
+	for i, event := range slices.Concat(groupByAggregate(events)...) {
+		// only the first event is checked, the rest of its aggregate's follow on from it within tx
+		if i > 0 {
+			expectedVersion = AnyVersion
+		}
+		err := o.insert(ctx, tx, expectedVersion, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *SQLiteEventStore) insert(ctx context.Context, tx *sql.Tx, expectedVersion int, event Event) error {
+	data, err := Serialize(event)
+	if err != nil {
+		return err
+	}
+
+	aggregateId := event.GetAggregateId()
+	version, err := o.currentVersion(ctx, tx, aggregateId)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(aggregateId, expectedVersion, version)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO events (aggregate_id, version, type, event_id, correlation_id, causation_id, data) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		aggregateId, version+1, TypeName(event), event.GetEventId(), event.GetCorrelationId(), event.GetCausationId(), data,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		// someone else got the next version in between our read and write
+		actualVersion, _ := o.currentVersion(ctx, tx, aggregateId)
+		return ErrConcurrencyConflict{
+			AggregateId:     aggregateId,
+			ExpectedVersion: version,
+			ActualVersion:   actualVersion,
+		}
+	}
+	return err
+}
+
+func (o *SQLiteEventStore) currentVersion(ctx context.Context, tx *sql.Tx, aggregateId string) (int, error) {
+	var version int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM events WHERE aggregate_id = ?`,
+		aggregateId,
+	).Scan(&version)
+	return version, err
+}
+
+func (o *SQLiteEventStore) NewGenerator(queryOptions ...QueryOption) GeneratorFn {
+	var query Query
+	for _, opt := range queryOptions {
+		opt(&query)
+	}
+
+	var buffer []Event
+	position := query.AfterPosition
+	returned := 0
+	exhausted := false
+	return func(ctx context.Context) (Event, error) {
+		if query.Limit > 0 && returned >= query.Limit {
+			return nil, nil
+		}
+
+		if len(buffer) == 0 {
+			// a short batch means we've caught up, so report the end once without
+			// another round trip; calling Next again picks up anything written since
+			if exhausted {
+				exhausted = false
+				return nil, nil
+			}
+
+			batchSize := sqliteGeneratorBatchSize
+			if query.Limit > 0 && query.Limit-returned < batchSize {
+				batchSize = query.Limit - returned
+			}
+			events, err := o.readBatch(ctx, query, position, batchSize)
+			if err != nil {
+				return nil, err
+			}
+			if len(events) == 0 {
+				return nil, nil
+			}
+			exhausted = len(events) < batchSize
+			position = events[len(events)-1].GetPosition()
+			buffer = events
+		}
+
+		event := buffer[0]
+		buffer = buffer[1:]
+		returned++
+		return event, nil
+	}
+}
+
+// Subscribe only sees events written through a caller's tx once it commits, NewCommandPipeline
+// notifies subscriptions then, anything else is picked up within subscriptionPollInterval
+func (o *SQLiteEventStore) Subscribe(ctx context.Context, fromPosition int64, queryOptions ...QueryOption) GeneratorFn {
+	return subscribe(ctx, o, &o.notifier, fromPosition, queryOptions)
+}
+
+func (o *SQLiteEventStore) readBatch(ctx context.Context, query Query, afterPosition int64, batchSize int) ([]Event, error) {
+	statement := `SELECT position, version, data FROM events WHERE position > ?`
+	args := []any{afterPosition}
+	if query.AggregateIds != nil {
+		statement += fmt.Sprintf(` AND aggregate_id IN (%s)`, placeholders(len(query.AggregateIds)))
+		for _, aggregateId := range query.AggregateIds {
+			args = append(args, aggregateId)
+		}
+	}
+	if query.Types != nil {
+		statement += fmt.Sprintf(` AND type IN (%s)`, placeholders(len(query.Types)))
+		for _, typeName := range query.Types {
+			args = append(args, typeName)
+		}
+	}
+	if query.CorrelationIds != nil {
+		statement += fmt.Sprintf(` AND correlation_id IN (%s)`, placeholders(len(query.CorrelationIds)))
+		for _, correlationId := range query.CorrelationIds {
+			args = append(args, correlationId)
+		}
+	}
+	if query.CausationIds != nil {
+		statement += fmt.Sprintf(` AND causation_id IN (%s)`, placeholders(len(query.CausationIds)))
+		for _, causationId := range query.CausationIds {
+			args = append(args, causationId)
+		}
+	}
+	statement += ` ORDER BY position LIMIT ?`
+	args = append(args, batchSize)
+
+	rows, err := o.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var position int64
+		var version int
+		var data string
+		err := rows.Scan(&position, &version, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		deserialized, err := Deserialize(data)
+		if err != nil {
+			return nil, fmt.Errorf("event at position %d: %w", position, err)
+		}
+		event, ok := deserialized.(Event)
+		if !ok {
+			return nil, fmt.Errorf("event at position %d: %T is not an Event", position, deserialized)
+		}
+
+		events = append(events, withPosition(event, position, version))
+	}
+	return events, rows.Err()
+}
+
+func (o *SQLiteEventStore) LoadSnapshot(ctx context.Context, aggregateId string) (*Snapshot, error) {
+	snapshot := Snapshot{AggregateId: aggregateId}
+	err := o.db.QueryRowContext(ctx,
+		`SELECT schema, version, position, data FROM snapshots WHERE aggregate_id = ?`,
+		aggregateId,
+	).Scan(&snapshot.Schema, &snapshot.Version, &snapshot.Position, &snapshot.Data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// SaveSnapshot only keeps the latest snapshot per aggregate
+func (o *SQLiteEventStore) SaveSnapshot(ctx context.Context, snapshot Snapshot) error {
+	_, err := o.db.ExecContext(ctx,
+		`INSERT INTO snapshots (aggregate_id, schema, version, position, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (aggregate_id) DO UPDATE SET
+			schema = excluded.schema,
+			version = excluded.version,
+			position = excluded.position,
+			data = excluded.data`,
+		snapshot.AggregateId, snapshot.Schema, snapshot.Version, snapshot.Position, snapshot.Data,
+	)
+	return err
+}
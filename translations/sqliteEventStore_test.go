@@ -0,0 +1,345 @@
+package translations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestSQLiteEventStore(t *testing.T) (*sql.DB, *SQLiteEventStore) {
+	db, err := sql.Open("sqlite3", SQLiteDSN(filepath.Join(t.TempDir(), "test.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	eventStore, err := NewSQLiteEventStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, eventStore
+}
+
+func TestSQLiteEventStore(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+
+	events := []Event{
+		ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "Project 1", Locales: []string{"en"}},
+		ProjectCreated{EventBase: NewEventBase(ctx, "p2"), Id: "p2", Name: "Project 2", Locales: []string{"en"}},
+		KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: "header_1"},
+		TranslationUpdated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", KeyId: "header_1", Id: "en", Value: "Hello"},
+	}
+	for _, event := range events {
+		err := eventStore.Write(ctx, nil, AnyVersion, event)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Name != "Project 1" {
+		t.Errorf("wrong project name %q", project.Name)
+	}
+	if project.KeysById["header_1"].TranslationsById["en"].Value != "Hello" {
+		t.Error("wrong translation value")
+	}
+
+	// a reopened store reads the same events back
+	eventStore, err = NewSQLiteEventStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectList, err := GetProjectList(ctx, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projectList.ProjectsById) != 2 {
+		t.Errorf("expected 2 projects, got %d", len(projectList.ProjectsById))
+	}
+}
+
+func TestSQLiteEventStoreConcurrencyConflict(t *testing.T) {
+	ctx := context.Background()
+	_, eventStore := newTestSQLiteEventStore(t)
+
+	err := eventStore.Write(ctx, nil, 0, ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "Project 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Version != 1 {
+		t.Fatalf("expected version 1, got %d", project.Version)
+	}
+
+	err = eventStore.Write(ctx, nil, project.Version, ProjectUpdated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a second writer that also read version 1 has to lose
+	err = eventStore.Write(ctx, nil, project.Version, ProjectUpdated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "second"})
+	var conflict ErrConcurrencyConflict
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected ErrConcurrencyConflict, got %v", err)
+	}
+	if conflict.ExpectedVersion != 1 || conflict.ActualVersion != 2 {
+		t.Errorf("wrong versions in %v", conflict)
+	}
+}
+
+func TestSQLiteEventStoreConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+
+	// p1 is due a snapshot, reading it alongside the writers mustn't try to take one
+	err := eventStore.Write(ctx, nil, AnyVersion, ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "Project 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < projectSnapshotInterval; i++ {
+		err := eventStore.Write(ctx, nil, AnyVersion, KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: fmt.Sprintf("key_%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createProject := NewCommandPipeline(db, eventStore, CreateProject())
+	const writers = 50
+	errs := make(chan error, 2*writers)
+	for i := 0; i < writers; i++ {
+		go func() {
+			errs <- createProject(ctx, CreateProjectInput{Name: fmt.Sprintf("Project %d", i)}, AnyVersion)
+		}()
+		go func() {
+			_, err := GetProject(ctx, eventStore, "p1")
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2*writers; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	projectList, err := GetProjectList(ctx, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projectList.ProjectsById) != writers+1 {
+		t.Errorf("expected %d projects, got %d", writers+1, len(projectList.ProjectsById))
+	}
+}
+
+func TestSQLiteEventStorePositions(t *testing.T) {
+	ctx := context.Background()
+	_, eventStore := newTestSQLiteEventStore(t)
+
+	for i := 0; i < sqliteGeneratorBatchSize+5; i++ {
+		aggregateId := fmt.Sprintf("p%d", i%2)
+		err := eventStore.Write(ctx, nil, AnyVersion, ProjectUpdated{EventBase: NewEventBase(ctx, aggregateId), Id: aggregateId})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var position int64
+	count := 0
+	generator := eventStore.NewGenerator()
+	for {
+		event, err := generator.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event == nil {
+			break
+		}
+		if event.GetPosition() <= position {
+			t.Fatalf("position %d after %d", event.GetPosition(), position)
+		}
+		position = event.GetPosition()
+		count++
+		if event.GetVersion() != (count+1)/2 {
+			t.Errorf("event %d has version %d", count, event.GetVersion())
+		}
+	}
+	if count != sqliteGeneratorBatchSize+5 {
+		t.Errorf("expected %d events, got %d", sqliteGeneratorBatchSize+5, count)
+	}
+
+	generator = eventStore.NewGenerator(AfterPosition(10), Limit(3))
+	for i := int64(11); i <= 13; i++ {
+		event, err := generator.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event == nil || event.GetPosition() != i {
+			t.Fatalf("expected event at position %d, got %v", i, event)
+		}
+	}
+	event, err := generator.Next(ctx)
+	if err != nil || event != nil {
+		t.Errorf("expected limit to end the generator, got %v, %v", event, err)
+	}
+}
+
+func TestSQLiteEventStoreSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, eventStore := newTestSQLiteEventStore(t)
+
+	err := eventStore.Write(ctx, nil, AnyVersion, ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "Project 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := eventStore.Subscribe(ctx, 0, EventTypes(projectListEventTypes...))
+	event, err := subscription.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetAggregateId() != "p1" {
+		t.Fatalf("expected history to be replayed first, got %v", event)
+	}
+
+	go func() {
+		createProject := NewCommandPipeline(db, eventStore, CreateProject())
+		createProject(ctx, CreateProjectInput{Name: "Project 2"}, 0)
+	}()
+
+	nextCtx, cancelNext := context.WithTimeout(ctx, subscriptionPollInterval/2)
+	defer cancelNext()
+	event, err = subscription.Next(nextCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.(ProjectCreated).Name != "Project 2" {
+		t.Errorf("expected the new project, got %v", event)
+	}
+
+	cancel()
+	_, err = subscription.Next(context.Background())
+	if err != context.Canceled {
+		t.Errorf("expected subscription to end with its context, got %v", err)
+	}
+}
+
+func TestSQLiteEventStoreSnapshots(t *testing.T) {
+	ctx := context.Background()
+	db, eventStore := newTestSQLiteEventStore(t)
+
+	err := eventStore.Write(ctx, nil, AnyVersion, ProjectCreated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: "Project 1", Locales: []string{"en"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < projectSnapshotInterval; i++ {
+		err := eventStore.Write(ctx, nil, AnyVersion, KeyCreated{EventBase: NewEventBase(ctx, "p1"), ProjectId: "p1", Id: fmt.Sprintf("key_%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// reading a project never writes, even once a snapshot is due
+	replayed, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := eventStore.LoadSnapshot(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot != nil {
+		t.Fatalf("expected no snapshot from reading, got one at version %d", snapshot.Version)
+	}
+
+	// the next command to write to it takes one
+	updateProject := NewCommandPipeline(db, eventStore, func(ctx context.Context, name string) ([]Event, error) {
+		return []Event{ProjectUpdated{EventBase: NewEventBase(ctx, "p1"), Id: "p1", Name: name}}, nil
+	})
+	err = updateProject(ctx, "Renamed", AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err = eventStore.LoadSnapshot(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot == nil || snapshot.Version != replayed.Version+1 {
+		t.Fatalf("expected a snapshot at version %d, got %v", replayed.Version+1, snapshot)
+	}
+
+	err = updateProject(ctx, "Renamed again", AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSnapshot, err := GetProject(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromSnapshot.Name != "Renamed again" || fromSnapshot.Version != replayed.Version+2 {
+		t.Errorf("expected the tail to be reduced onto the snapshot, got %q at version %d", fromSnapshot.Name, fromSnapshot.Version)
+	}
+	if len(fromSnapshot.KeysById) != projectSnapshotInterval {
+		t.Error("expected keys to be restored from the snapshot")
+	}
+	if strings.Contains(snapshot.Data, "History") {
+		t.Error("expected the snapshot to leave out the project's history")
+	}
+
+	history, err := GetProjectHistory(ctx, eventStore, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != fromSnapshot.Version || TypeName(history[0]) != "ProjectUpdated" || TypeName(history[len(history)-1]) != "ProjectCreated" {
+		t.Errorf("expected all %d events of p1 newest first, got %d", fromSnapshot.Version, len(history))
+	}
+}
+
+func TestSQLiteEventStoreCorrelation(t *testing.T) {
+	ctx := WithRequestId(context.Background(), "request-1")
+	db, eventStore := newTestSQLiteEventStore(t)
+	err := eventStore.Write(context.Background(), nil, 0, ProjectCreated{EventBase: NewEventBase(context.Background(), "p1"), Id: "p1", Locales: []string{"en", "es"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createKey := NewCommandPipeline(db, eventStore, CreateKey(eventStore))
+	err = createKey(ctx, CreateKeyInput{ProjectId: "p1", Id: "header_1", Values: map[string]string{"en": "Hello", "es": "Hola"}}, AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = createKey(context.Background(), CreateKeyInput{ProjectId: "p1", Id: "header_2"}, AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var projectList ProjectList
+	err = ReduceWith(ctx, &projectList, eventStore.NewGenerator(CorrelationIds("request-1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projectList.History) != 3 {
+		t.Errorf("expected the 3 events from request-1, got %d", len(projectList.History))
+	}
+
+	event, err := eventStore.NewGenerator(CausationIds("request-1")).Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetEventId() == "" || event.GetMetadata()["requestId"] != "request-1" {
+		t.Errorf("expected an id and request metadata on %+v", event)
+	}
+}
@@ -0,0 +1,85 @@
+package translations
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+Subscriptions
+- a subscription is a generator that replays history and then blocks for new events instead of ending
+- it's pull based: the store never waits on a slow subscriber, the subscriber just reads further behind
+- it ends with an error once its context is done
+*/
+
+// subscriptionPollInterval bounds how long a subscription can miss a write it wasn't notified about,
+// e.g. one made by another process against the same database
+const subscriptionPollInterval = 500 * time.Millisecond
+
+// notifier wakes up everyone waiting on it whenever notify is called
+type notifier struct {
+	lock sync.Mutex
+	ch   chan struct{}
+}
+
+func (o *notifier) wait() <-chan struct{} {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.ch == nil {
+		o.ch = make(chan struct{})
+	}
+	return o.ch
+}
+
+func (o *notifier) notify() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.ch != nil {
+		close(o.ch)
+		o.ch = nil
+	}
+}
+
+// notifyCommitted wakes up the event store's subscriptions after a transaction it wrote to has committed
+func notifyCommitted(eventStore EventStore) {
+	if n, ok := eventStore.(interface{ notify() }); ok {
+		n.notify()
+	}
+}
+
+func subscribe(ctx context.Context, eventStore EventStore, n *notifier, fromPosition int64, queryOptions []QueryOption) GeneratorFn {
+	position := fromPosition
+	var generator GeneratorFn
+	return func(nextCtx context.Context) (Event, error) {
+		for {
+			// grab the wait channel before reading so a write that lands in between still wakes us
+			wake := n.wait()
+
+			if generator == nil {
+				opts := append([]QueryOption{}, queryOptions...)
+				generator = eventStore.NewGenerator(append(opts, AfterPosition(position))...)
+			}
+			event, err := generator.Next(nextCtx)
+			if err != nil {
+				return nil, err
+			}
+			if event != nil {
+				position = event.GetPosition()
+				return event, nil
+			}
+
+			generator = nil
+			select {
+			case <-wake:
+			case <-time.After(subscriptionPollInterval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-nextCtx.Done():
+				return nil, nextCtx.Err()
+			}
+		}
+	}
+}
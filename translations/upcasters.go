@@ -0,0 +1,119 @@
+package translations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+/*
+Upcasters
+- events are stored forever, so when a struct changes its old payloads have to keep deserializing
+- an upcaster migrates a payload from one schema version of a type to the next, at read time
+- a type starts at schema version 1 and gains a version for every upcaster registered for it
+*/
+type Upcaster func(payload map[string]any) (map[string]any, error)
+
+// RegisterUpcaster adds the migration from fromVersion to fromVersion+1, they have to be registered in order
+func (o *Registry) RegisterUpcaster(name string, fromVersion int, upcaster Upcaster) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.typesByName[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrorUnknownName, name)
+	}
+	currentVersion := len(o.upcastersByName[name]) + 1
+	if fromVersion != currentVersion {
+		return fmt.Errorf("upcaster for %s from version %d: current version is %d", name, fromVersion, currentVersion)
+	}
+	o.upcastersByName[name] = append(o.upcastersByName[name], upcaster)
+	return nil
+}
+
+// SchemaVersion is the version payloads registered under name are serialized with
+func (o *Registry) SchemaVersion(name string) int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return len(o.upcastersByName[name]) + 1
+}
+
+// upcast migrates a payload written at version up to the current schema version of name
+func (o *Registry) upcast(name string, version int, payload string) (string, error) {
+	o.mu.RLock()
+	upcasters := o.upcastersByName[name]
+	o.mu.RUnlock()
+
+	// payloads written before schema versions existed are version 1
+	if version == 0 {
+		version = 1
+	}
+	if version > len(upcasters)+1 {
+		return "", fmt.Errorf("%s payload has version %d, newer than the current version %d", name, version, len(upcasters)+1)
+	}
+	if version == len(upcasters)+1 {
+		return payload, nil
+	}
+
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewBufferString(payload))
+	decoder.UseNumber() // keep large integers like positions intact
+	err := decoder.Decode(&fields)
+	if err != nil {
+		return "", err
+	}
+
+	for v := version; v <= len(upcasters); v++ {
+		fields, err = upcasters[v-1](fields)
+		if err != nil {
+			return "", fmt.Errorf("upcasting %s from version %d: %w", name, v, err)
+		}
+	}
+
+	upcasted, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(upcasted), nil
+}
+
+func RegisterUpcaster(name string, fromVersion int, upcaster Upcaster) error {
+	return DefaultRegistry.RegisterUpcaster(name, fromVersion, upcaster)
+}
+
+// registerEventUpcasters brings the events stored before a change to their struct up to date, so reducers only see current events
+func registerEventUpcasters(registry *Registry) {
+	for _, upcaster := range []struct {
+		name        string
+		fromVersion int
+		upcaster    Upcaster
+	}{
+		// projects created before locales were configurable have no Locales, they were all in legacyLocales
+		{"ProjectCreated", 1, func(payload map[string]any) (map[string]any, error) {
+			if payload["Locales"] == nil {
+				payload["Locales"] = legacyLocales
+			}
+			return payload, nil
+		}},
+		// keys moved before review existed have no Statuses, their values were drafts like every other value then
+		{"KeyMoved", 1, func(payload map[string]any) (map[string]any, error) {
+			if payload["Statuses"] != nil {
+				return payload, nil
+			}
+			values, _ := payload["Values"].(map[string]any)
+			statuses := map[string]any{}
+			for locale, value := range values {
+				if value != "" {
+					statuses[locale] = StatusDraft
+				}
+			}
+			payload["Statuses"] = statuses
+			return payload, nil
+		}},
+	} {
+		err := registry.RegisterUpcaster(upcaster.name, upcaster.fromVersion, upcaster.upcaster)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
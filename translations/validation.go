@@ -0,0 +1,47 @@
+package translations
+
+import (
+	"sort"
+	"strings"
+)
+
+/*
+ValidationError
+- returned by commands when their input can't be turned into events
+- Fields maps the name of the offending input field to a message that can be shown to the user as is
+*/
+type ValidationError struct {
+	Fields map[string]string
+}
+
+// Add records a problem with field, only the first one per field is kept
+func (o *ValidationError) Add(field string, message string) {
+	if o.Fields == nil {
+		o.Fields = map[string]string{}
+	}
+	if _, ok := o.Fields[field]; !ok {
+		o.Fields[field] = message
+	}
+}
+
+// Err returns the ValidationError if anything was added to it, nil otherwise
+func (o ValidationError) Err() error {
+	if len(o.Fields) == 0 {
+		return nil
+	}
+	return o
+}
+
+func (o ValidationError) Error() string {
+	fields := make([]string, 0, len(o.Fields))
+	for field := range o.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field+": "+o.Fields[field])
+	}
+	return "invalid input: " + strings.Join(messages, ", ")
+}